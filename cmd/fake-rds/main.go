@@ -0,0 +1,38 @@
+// fake-rds serves an in memory stand-in for the AWS RDS Query API, for integration tests of rds-snapshot-copier
+// without AWS. Point the copier at it with RDS_ENDPOINT="*=http://localhost:4597" and any static credentials.
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/fakerds"
+)
+
+func main() {
+	app := kingpin.New("fake-rds", "An in memory stand-in for the AWS RDS API, for integration tests")
+	listen := app.Flag("listen", "Serve on this address").Short('L').Default("localhost:4597").Envar("LISTEN_ADDR").String()
+	seed := app.Flag("seed", "JSON file of the rds instances and snapshots to start with, by region").Short('s').Envar("SEED_FILE").ExistingFile()
+	steps := app.Flag("steps", "Polls a copy takes to complete").Short('n').Default("3").Envar("COPY_STEPS").Int()
+	kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	c := fakerds.New()
+	c.Steps = *steps
+	if *seed != "" {
+		f, err := os.Open(*seed)
+		if err != nil {
+			log.Fatalf("Unable to read the seed: %s", err.Error())
+		}
+		err = c.Load(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("Unable to load the seed: %s", err.Error())
+		}
+	}
+
+	log.Printf("Serving a fake RDS API on %s", *listen)
+	log.Fatal(http.ListenAndServe(*listen, fakerds.Handler(c)))
+}
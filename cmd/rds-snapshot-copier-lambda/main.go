@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"go.uber.org/zap"
+
+	"github.com/bluebenno/rds-snapshot-copier/cmd/rds-snapshot-copier/flags"
+	"github.com/bluebenno/rds-snapshot-copier/internal/events"
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+	"github.com/bluebenno/rds-snapshot-copier/internal/worker"
+)
+
+const (
+	appName   = "rds-snapshot-copier-lambda"
+	gitCommit = "dirty"
+	version   = "devbuild"
+	// margin is how long before the Lambda timeout the run is stopped, leaving time to return its report
+	margin = 10 * time.Second
+)
+
+// payload is the invocation event. It may also be an RDS snapshot event, e.g. from an EventBridge rule, in which
+// case only the rds it is for is copied; any other event, e.g. a scheduled one, runs a whole cycle.
+type payload struct {
+	Config map[string]string `json:"config"` // Overrides the environmental variables, e.g. {"TARGET_REGION": "us-west-2"}
+}
+
+func main() {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		log.Fatalf("Unable to create logger: %s", err.Error())
+	}
+
+	lambda.Start(func(ctx context.Context, raw json.RawMessage) (*worker.RunReport, error) {
+		return handle(ctx, logger, raw)
+	})
+}
+
+// handle runs a single cycle, or copies the rds of a snapshot event, returning its report. Copies are started but
+// not waited for; each invocation checks on those left in progress by the last. Being stopped by the Lambda timeout
+// is not an error, the next invocation carries on.
+func handle(ctx context.Context, logger *zap.Logger, raw json.RawMessage) (*worker.RunReport, error) {
+	var p payload
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil, fmt.Errorf("invalid payload: %v", err)
+		}
+	}
+	cfg, err := config(p.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline.Add(-margin))
+		defer cancel()
+	}
+
+	var report *worker.RunReport
+	ev, everr := events.Parse(raw)
+	switch {
+	case everr == nil && ev == nil:
+		logger.Info("Ignoring RDS event, it is not a snapshot being created")
+		return worker.NewRunReport(cfg.SourceRegion), nil
+	case ev != nil:
+		report, err = worker.CopyFor(ctx, logger, cfg, ev)
+	default:
+		report, err = worker.Cycle(ctx, logger, cfg)
+	}
+	report.Log(logger)
+
+	if err != nil && ctx.Err() == nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// config reads the config from the environmental variables, as overridden by the payload. The environment is
+// restored afterwards, as it outlives the invocation.
+func config(overrides map[string]string) (*wiring.Config, error) {
+	for k, v := range overrides {
+		old, set := os.LookupEnv(k)
+		os.Setenv(k, v)
+		if set {
+			defer os.Setenv(k, old)
+		} else {
+			defer os.Unsetenv(k)
+		}
+	}
+
+	var cfg wiring.Config
+	if err := Flags.Env(appName, gitCommit, version, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+	cfg.NoWait = true
+	cfg.Once = true
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config, %v", err)
+	}
+	return &cfg, nil
+}
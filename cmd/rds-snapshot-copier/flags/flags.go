@@ -1,29 +1,127 @@
 package Flags
 
 import (
+	"fmt"
 	"os"
 
 	"gopkg.in/alecthomas/kingpin.v2"
 
+	"github.com/bluebenno/rds-snapshot-copier/internal/configfile"
 	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
 )
 
-// Flags parses the command line flags and or environmental variables
-func Flags(name, gitCommit, version string, cfg *wiring.Config) *kingpin.Application {
+// Flags parses the command line flags and or environmental variables, and the config file if one is given.
+// It returns the command to run, "run" or "check"
+func Flags(name, gitCommit, version string, cfg *wiring.Config) (*kingpin.Application, string) {
+	app, cmd, err := parse(name, gitCommit, version, cfg, os.Args[1:])
+	kingpin.MustParse(cmd, err)
+
+	return app, cmd
+}
+
+// Load parses the command line flags and or environmental variables, and the config file, afresh into a new
+// config, e.g. to reload it. Unlike Flags, an error is returned rather than exiting.
+func Load(name, gitCommit, version string) (*wiring.Config, error) {
+	var cfg wiring.Config
+	if _, _, err := parse(name, gitCommit, version, &cfg, os.Args[1:]); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Env parses the environmental variables alone, and the config file if one is given, where there is no command
+// line, e.g. in AWS Lambda
+func Env(name, gitCommit, version string, cfg *wiring.Config) error {
+	_, _, err := parse(name, gitCommit, version, cfg, nil)
+	return err
+}
+
+// parse parses args, then again with the config file's values as the defaults of the flags. So the command line
+// takes precedence over environmental variables, which take precedence over the config file.
+func parse(name, gitCommit, version string, cfg *wiring.Config, args []string) (*kingpin.Application, string, error) {
+	app := newApp(name, gitCommit, version, cfg)
+	cmd, err := app.Parse(args)
+	if err != nil || cfg.ConfigFile == "" {
+		return app, cmd, err
+	}
+
+	filename := cfg.ConfigFile
+	f, err := configfile.Load(filename)
+	if err != nil {
+		return app, "", err
+	}
+
+	*cfg = wiring.Config{}
+	app = newApp(name, gitCommit, version, cfg)
+	for k, v := range f.Defaults {
+		flag := app.GetFlag(k)
+		if flag == nil || k == "config" {
+			return app, "", fmt.Errorf("%s: unknown setting %q in defaults", filename, k)
+		}
+		flag.Default(v...)
+	}
+	cmd, err = app.Parse(args)
+	if err != nil {
+		return app, "", fmt.Errorf("%s: %v", filename, err)
+	}
+	cfg.Overrides = f.Overrides
+	return app, cmd, nil
+}
+
+// newApp defines the flags, which set cfg when parsed
+func newApp(name, gitCommit, version string, cfg *wiring.Config) *kingpin.Application {
 
 	app := kingpin.New(name, "An AWS rds snapshot copier that has region and encryption support")
 
+	// kingpin adds to the maps of repeatable flags, rather than making them
+	if cfg.Endpoints == nil {
+		cfg.Endpoints = make(map[string]string)
+	}
+	if cfg.TargetKMSRegion == nil {
+		cfg.TargetKMSRegion = make(map[string]string)
+	}
+
+	app.Flag("apiburst", "Calls to each AWS API, per region, may burst to this many").Short('B').Default("10").Envar("API_BURST").IntVar(&cfg.APIBurst)
+	app.Flag("apirate", "Maximum calls per second to each AWS API, per region. 0 is unlimited").Short('q').Default("5").Envar("API_RATE").Float64Var(&cfg.APIRate)
+	app.Flag("apiretries", "Retry throttled and transiently failing AWS API calls this many times").Short('n').Default("8").Envar("API_RETRIES").IntVar(&cfg.APIRetries)
+	app.Flag("apiretrybase", "Back off by about this long before the first retry of an AWS API call, doubling each retry").Short('x').Default("1s").Envar("API_RETRY_BASE").DurationVar(&cfg.APIRetryBase)
+	app.Flag("apiretrymax", "Maximum back off between retries of an AWS API call. 0 is no cap").Short('X').Default("1m").Envar("API_RETRY_MAX").DurationVar(&cfg.APIRetryMax)
+	app.Flag("config", "YAML, JSON or TOML file of defaults for these flags, and per rds overrides of the copy policy").Short('C').Envar("CONFIG_FILE").ExistingFileVar(&cfg.ConfigFile)
+	app.Flag("copytimeout", "Abort a snapshot copy still in progress after this long, e.g. 12h. 0 waits forever").Short('c').Default("0").Envar("COPY_TIMEOUT").DurationVar(&cfg.CopyTimeout)
 	app.Flag("dryrun", "do a dry run, print what can be done").Short('d').Envar("DRY_RUN").BoolVar(&cfg.DryRun)
+	app.Flag("endpoint", `Use this RDS endpoint URL for a region, as region=url, e.g. a local stand-in. Repeatable, "*=url" for every region`).Short('u').Envar("RDS_ENDPOINT").StringMapVar(&cfg.Endpoints)
+	app.Flag("eventqueue", "Copy snapshots as soon as their RDS events arrive on this SQS queue URL").Short('Q').Envar("EVENT_QUEUE_URL").StringVar(&cfg.EventQueue)
+	app.Flag("eventwebhook", "Copy snapshots as soon as their RDS events are POSTed to /events on the listen address").Short('E').Envar("EVENT_WEBHOOK").BoolVar(&cfg.EventWebhook)
+	app.Flag("externalid", "The external ID used when assuming the source or target role").Short('e').Envar("EXTERNAL_ID").StringVar(&cfg.ExternalID)
+	app.Flag("keepdaily", "Keep the latest snapshot of each of this many days, in target region").Short('D').Default("0").Envar("KEEP_DAILY").IntVar(&cfg.KeepDaily)
+	app.Flag("keepmonthly", "Keep the latest snapshot of each of this many months, in target region").Short('M').Default("0").Envar("KEEP_MONTHLY").IntVar(&cfg.KeepMonthly)
+	app.Flag("keepweekly", "Keep the latest snapshot of each of this many weeks, in target region").Short('W').Default("0").Envar("KEEP_WEEKLY").IntVar(&cfg.KeepWeekly)
+	app.Flag("livecycles", "/healthz fails if no loop completes within this many times runevery. 0 never fails").Short('v').Default("3").Envar("LIVENESS_CYCLES").IntVar(&cfg.LiveCycles)
+	app.Flag("listen", `Serve HTTP endpoints, such as Prometheus metrics on /metrics, on this address, e.g. ":9090"`).Short('L').Envar("LISTEN_ADDR").StringVar(&cfg.ListenAddr)
 	app.Flag("loglevel", `log level: "debug", "info", "warn", "error", "dpanic", "panic", and "fatal".`).Short('l').Envar("LOG_LEVEL").Default("info").EnumVar(&cfg.LogLevel, "debug", "info", "warn", "error", "dpanic", "panic", "fatal")
+	app.Flag("lookback", "Copy every snapshot taken within this window, e.g. 72h, that is missing from the target region. 0 copies only the latest").Short('b').Default("0").Envar("LOOKBACK").DurationVar(&cfg.Lookback)
+	app.Flag("maxagedays", "Keep snapshots younger than this many days, in target region").Short('A').Default("0").Envar("MAX_AGE_DAYS").IntVar(&cfg.MaxAgeDays)
 	app.Flag("maxinflight", "Maximum copy operations in flight. AWS max is six").Short('f').Default("2").Envar("MAX_SNAPSHOT_FLIGHT").IntVar(&cfg.MaxCopyInFlight)
 	app.Flag("maxsnapshots", "Maximum number of Snapshots per rds, to keep in target region").Short('m').Default("0").Envar("MAX_SNAPSHOT_TARGET").IntVar(&cfg.MaxSnap)
-	app.Flag("runevery", "How often should the Source Region be polled for new snapshots, in minutes").Short('r').Default("0").Envar("RUN_EVERY_MINS").IntVar(&cfg.RunEvery)
+	app.Flag("nowait", "Start copies, and check on those in progress, without waiting for them to finish").Short('w').Envar("NO_WAIT").BoolVar(&cfg.NoWait)
+	app.Flag("once", "Run once and exit, non-zero on failure, rather than polling every runevery minutes").Short('o').Envar("RUN_ONCE").BoolVar(&cfg.Once)
+	app.Flag("report", `Write a JSON report of each run to this file, or "-" for stdout`).Short('j').Envar("REPORT_FILE").StringVar(&cfg.Report)
+	app.Flag("rpo", "The newest copy of each rds must be younger than this, e.g. 26h. 0 only requires a copy to exist").Short('P').Default("0").Envar("RPO").DurationVar(&cfg.RPO)
+	app.Flag("runevery", "How often should the Source Region be polled for new snapshots, in minutes").Short('r').Default("60").Envar("RUN_EVERY_MINS").IntVar(&cfg.RunEvery)
+	app.Flag("schedule", "Minimum time between copies of an rds's snapshots, e.g. 24h. 0 copies every new snapshot").Short('S').Default("0").Envar("COPY_SCHEDULE").DurationVar(&cfg.Schedule)
+	app.Flag("snapshottype", `snapshot kind to copy: "instance" (rds), "cluster" (Aurora) or "all".`).Short('y').Default("instance").Envar("SNAPSHOT_TYPE").EnumVar(&cfg.SnapshotType, "instance", "cluster", "all")
 	app.Flag("sourceregion", "AWS Source Region").Short('s').Envar("SOURCE_REGION").StringVar(&cfg.SourceRegion)
+	app.Flag("sourcerole", "IAM role ARN to assume in the source account").Short('R').Envar("SOURCE_ROLE_ARN").StringVar(&cfg.SourceRoleARN)
+	app.Flag("staticaccesskey", "Use this static AWS access key ID, e.g. for a local stand-in, rather than the ambient credentials").Short('i').Envar("STATIC_ACCESS_KEY_ID").StringVar(&cfg.StaticKeyID)
+	app.Flag("staticsecretkey", "The secret access key of staticaccesskey").Short('I').Envar("STATIC_SECRET_ACCESS_KEY").StringVar(&cfg.StaticSecret)
 	app.Flag("tag", "rds with the value tag will have their snapshots copied").Short('a').Envar("TAG").StringVar(&cfg.Tag)
 	app.Flag("targetkms", "Encrypt the snapshot at the target with KMS key").Short('k').Default("").Envar("TARGET_KMS").StringVar(&cfg.TargetKMS)
-	app.Flag("targetregion", "AWS Target Region").Short('t').Envar("TARGET_REGION").StringVar(&cfg.TargetRegion)
+	app.Flag("targetkmsregion", "Encrypt the snapshot at a target region with a KMS key, as region=key. Repeatable, overrides targetkms").Short('K').Envar("TARGET_KMS_REGION").StringMapVar(&cfg.TargetKMSRegion)
+	app.Flag("targetregion", "AWS Target Region(s), comma separated. Used when the tag value does not name regions").Short('t').Envar("TARGET_REGION").StringVar(&cfg.TargetRegion)
+	app.Flag("targetrole", "IAM role ARN to assume in the target account. If it is another account, snapshots are shared with it to be copied").Short('T').Envar("TARGET_ROLE_ARN").StringVar(&cfg.TargetRoleARN)
 
-	kingpin.MustParse(app.Parse(os.Args[1:]))
+	app.Command("run", "Copy snapshots to the target region(s). The default").Default()
+	app.Command("check", "Check the newest copy of each in scope rds is within its RPO, exiting non-zero if any is not")
 
 	return app
 }
@@ -1,7 +1,13 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/bluebenno/rds-snapshot-copier/cmd/rds-snapshot-copier/flags"
@@ -14,17 +20,18 @@ const (
 	appName   = "rds-snapshot-copier"
 	gitCommit = "dirty"
 	version   = "devbuild"
-	// AntiRateLimit will be slept in key places, to preclude AWS API rate-limiting.
-	AntiRateLimit = (100 * time.Millisecond)
 )
 
 func main() {
 	var cfg wiring.Config
 
-	app := Flags.Flags(appName, gitCommit, version, &cfg)
+	app, cmd := Flags.Flags(appName, gitCommit, version, &cfg)
 	if app == nil {
 		log.Fatalf("Failed to parse flags")
 	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid config, %s", err.Error())
+	}
 
 	logger, err := zap.NewProduction()
 	if err != nil {
@@ -32,8 +39,71 @@ func main() {
 	}
 	logger.Info("Starting")
 
-	err2 := worker.Run(logger, &cfg)
+	// The first SIGTERM or interrupt stops gracefully, a second exits immediately
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		s := <-sigs
+		logger.Info("Stopping", zap.String("signal", s.String()))
+		cancel()
+		<-sigs
+		logger.Warn("Stopping immediately")
+		os.Exit(1)
+	}()
+
+	if cmd == "check" {
+		check(ctx, logger, &cfg)
+		return
+	}
+
+	// The config is reloaded on SIGHUP, and when the config file changes
+	reload := worker.NewReloader(logger, &cfg, func() (*wiring.Config, error) {
+		return Flags.Load(appName, gitCommit, version)
+	})
+	hups := make(chan os.Signal, 1)
+	signal.Notify(hups, syscall.SIGHUP)
+	go func() {
+		for range hups {
+			logger.Info("Reloading the config", zap.String("signal", "hangup"))
+			reload.Reload()
+		}
+	}()
+	if cfg.ConfigFile != "" {
+		go reload.Watch(ctx, cfg.ConfigFile)
+	}
+
+	err2 := worker.Run(ctx, logger, &cfg, reload)
 	if err2 != nil {
-		log.Fatalf("Failed to parse flags: %+v", err2)
+		logger.Fatal("Run failed", zap.Error(err2))
+	}
+}
+
+// check prints the lag of every in scope rds, and exits non-zero if any breaches its RPO
+func check(ctx context.Context, logger *zap.Logger, cfg *wiring.Config) {
+	lags, err := worker.Check(ctx, logger, cfg)
+	if err != nil {
+		logger.Fatal("Check failed", zap.Error(err))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RDS\tTARGET REGION\tNEWEST COPY\tAGE\tLAG\tRPO\tSTATUS")
+	for _, l := range lags {
+		newest, state := "-", "ok"
+		if l.NewestCopy != nil {
+			newest = l.NewestCopy.UTC().Format(time.RFC3339)
+		}
+		if l.Breached {
+			state = "BREACHED"
+			if l.Error != "" {
+				state += ": " + l.Error
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", l.Source, l.TargetRegion, newest, l.Age.Round(time.Minute), l.Lag.Round(time.Minute), l.RPO, state)
+	}
+	w.Flush()
+
+	if b := worker.Breached(lags); len(b) > 0 {
+		logger.Fatal("RPO breached", zap.Int("breached", len(b)), zap.Int("checked", len(lags)))
 	}
 }
@@ -0,0 +1,203 @@
+// Package configfile reads the optional config file: defaults for the command line flags, and overrides of the copy
+// policy of the rds instances and Aurora clusters matching a pattern. It may be YAML, JSON or TOML, e.g.
+//
+//	defaults:
+//	  sourceregion: ap-southeast-2
+//	  targetregion: us-west-2
+//	  maxsnapshots: 7
+//	overrides:
+//	  - match: prod-*
+//	    enabled: true
+//	    regions: [us-west-2, eu-west-1]
+//	    keep: 14
+//	  - match: prod-reporting
+//	    enabled: false
+package configfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/policy"
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+// File is a config file
+type File struct {
+	Defaults  map[string][]string // Flag values, by flag name, e.g. "targetregion": {"us-west-2"}. Maps are "key=value"
+	Overrides []wiring.Override
+}
+
+// raw is a config file as decoded, before its values are checked
+type raw struct {
+	Defaults  map[string]interface{}   `json:"defaults" yaml:"defaults" toml:"defaults"`
+	Overrides []map[string]interface{} `json:"overrides" yaml:"overrides" toml:"overrides"`
+}
+
+// Load reads a config file, its format given by its extension: .yaml, .yml, .json or .toml
+func Load(filename string) (*File, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	f, err := Parse(b, strings.TrimPrefix(filepath.Ext(filename), "."))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return f, nil
+}
+
+// Parse parses a config file in format "yaml", "json" or "toml". Unknown keys are an error, as is an override
+// with an invalid pattern or copy policy.
+func Parse(b []byte, format string) (*File, error) {
+	var r raw
+	switch strings.ToLower(format) {
+	case "yaml", "yml":
+		if err := yaml.UnmarshalStrict(b, &r); err != nil {
+			return nil, err
+		}
+	case "json":
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.DisallowUnknownFields()
+		if err := d.Decode(&r); err != nil {
+			return nil, err
+		}
+	case "toml":
+		md, err := toml.Decode(string(b), &r)
+		if err != nil {
+			return nil, err
+		}
+		// Keys within defaults and overrides are decoded as interface{}, yet reported as undecoded
+		for _, k := range md.Undecoded() {
+			if len(k) == 1 {
+				return nil, fmt.Errorf("unknown key %q", k.String())
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown config file format %q, want yaml, json or toml", format)
+	}
+
+	f := &File{Defaults: make(map[string][]string)}
+	for k, v := range r.Defaults {
+		vals, err := values(v)
+		if err != nil {
+			return nil, fmt.Errorf("defaults: %s: %v", k, err)
+		}
+		f.Defaults[k] = vals
+	}
+	for n, o := range r.Overrides {
+		ov, err := override(o)
+		if err != nil {
+			return nil, fmt.Errorf("overrides[%d]: %v", n, err)
+		}
+		f.Overrides = append(f.Overrides, ov)
+	}
+	return f, nil
+}
+
+// override checks a single override. Other than match and enabled, its keys are those of a copy policy tag, and
+// lists of regions are joined as in the tag.
+func override(o map[string]interface{}) (wiring.Override, error) {
+	ov := wiring.Override{Policy: make(map[string]string)}
+	for k, v := range o {
+		vals, err := values(v)
+		if err != nil {
+			return ov, fmt.Errorf("%s: %v", k, err)
+		}
+		switch k {
+		case "match":
+			if len(vals) != 1 || vals[0] == "" {
+				return ov, fmt.Errorf("match must be an identifier or pattern")
+			}
+			if _, err := path.Match(vals[0], ""); err != nil {
+				return ov, fmt.Errorf("match %q: %v", vals[0], err)
+			}
+			ov.Match = vals[0]
+		case "enabled":
+			b, err := strconv.ParseBool(strings.Join(vals, ""))
+			if err != nil {
+				return ov, fmt.Errorf("enabled must be true or false")
+			}
+			ov.Enabled = &b
+		default:
+			ov.Policy[k] = strings.Join(vals, "+")
+		}
+	}
+	if ov.Match == "" {
+		return ov, fmt.Errorf("match is required")
+	}
+	if _, err := policy.FromMap(ov.Policy); err != nil {
+		return ov, fmt.Errorf("%q: %v", ov.Match, err)
+	}
+	return ov, nil
+}
+
+// values converts a decoded value into flag values: a scalar is a single value, a list one per item and a map
+// one "key=value" per entry, ordered by key
+func values(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case []interface{}:
+		var res []string
+		for _, i := range v {
+			s, err := scalar(i)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, s)
+		}
+		return res, nil
+	case map[string]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+		for k, i := range v {
+			m[k] = i
+		}
+		return values(m)
+	case map[interface{}]interface{}:
+		var res []string
+		for k, i := range v {
+			ks, err := scalar(k)
+			if err != nil {
+				return nil, err
+			}
+			s, err := scalar(i)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, ks+"="+s)
+		}
+		sort.Strings(res)
+		return res, nil
+	}
+	s, err := scalar(v)
+	if err != nil {
+		return nil, err
+	}
+	return []string{s}, nil
+}
+
+// scalar converts a decoded string, number or bool into a flag value
+func scalar(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", v)
+}
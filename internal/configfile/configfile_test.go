@@ -0,0 +1,143 @@
+package configfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	yes, no := true, false
+	want := &File{
+		Defaults: map[string][]string{
+			"sourceregion":    {"ap-southeast-2"},
+			"targetregion":    {"us-west-2"},
+			"maxsnapshots":    {"7"},
+			"dryrun":          {"true"},
+			"targetkmsregion": {"eu-west-1=alias/dr", "us-west-2=alias/dr-west"},
+		},
+		Overrides: []wiring.Override{
+			{Match: "prod-*", Enabled: &yes, Policy: map[string]string{"regions": "us-west-2+eu-west-1", "keep": "14", "schedule": "daily"}},
+			{Match: "prod-reporting", Enabled: &no, Policy: map[string]string{}},
+		},
+	}
+
+	type args struct {
+		format string
+		body   string
+	}
+	tests := []struct {
+		name string
+		args args
+		err  bool
+	}{
+		{
+			name: "Parse_yaml",
+			args: args{format: "yaml", body: `
+defaults:
+  sourceregion: ap-southeast-2
+  targetregion: us-west-2
+  maxsnapshots: 7
+  dryrun: true
+  targetkmsregion:
+    us-west-2: alias/dr-west
+    eu-west-1: alias/dr
+overrides:
+  - match: prod-*
+    enabled: true
+    regions: [us-west-2, eu-west-1]
+    keep: 14
+    schedule: daily
+  - match: prod-reporting
+    enabled: false
+`},
+		},
+		{
+			name: "Parse_json",
+			args: args{format: "json", body: `{
+  "defaults": {"sourceregion": "ap-southeast-2", "targetregion": "us-west-2", "maxsnapshots": 7, "dryrun": true,
+               "targetkmsregion": {"us-west-2": "alias/dr-west", "eu-west-1": "alias/dr"}},
+  "overrides": [
+    {"match": "prod-*", "enabled": true, "regions": ["us-west-2", "eu-west-1"], "keep": 14, "schedule": "daily"},
+    {"match": "prod-reporting", "enabled": false}
+  ]
+}`},
+		},
+		{
+			name: "Parse_toml",
+			args: args{format: "toml", body: `
+[defaults]
+sourceregion = "ap-southeast-2"
+targetregion = "us-west-2"
+maxsnapshots = 7
+dryrun = true
+targetkmsregion = { us-west-2 = "alias/dr-west", eu-west-1 = "alias/dr" }
+
+[[overrides]]
+match = "prod-*"
+enabled = true
+regions = ["us-west-2", "eu-west-1"]
+keep = 14
+schedule = "daily"
+
+[[overrides]]
+match = "prod-reporting"
+enabled = false
+`},
+		},
+		{name: "Parse_unknown_format", args: args{format: "ini", body: ``}, err: true},
+		{name: "Parse_unknown_key", args: args{format: "yaml", body: "default:\n  targetregion: us-west-2\n"}, err: true},
+		{name: "Parse_unknown_table", args: args{format: "toml", body: "[default]\ntargetregion = \"us-west-2\"\n"}, err: true},
+		{name: "Parse_no_match", args: args{format: "json", body: `{"overrides": [{"keep": 3}]}`}, err: true},
+		{name: "Parse_bad_pattern", args: args{format: "json", body: `{"overrides": [{"match": "prod-["}]}`}, err: true},
+		{name: "Parse_bad_policy", args: args{format: "json", body: `{"overrides": [{"match": "prod-*", "keep": "lots"}]}`}, err: true},
+		{name: "Parse_bad_enabled", args: args{format: "json", body: `{"overrides": [{"match": "prod-*", "enabled": "maybe"}]}`}, err: true},
+		{name: "Parse_nested_list", args: args{format: "json", body: `{"defaults": {"targetregion": [["us-west-2"]]}}`}, err: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := Parse([]byte(tt.args.body), tt.args.format)
+			if (err != nil) != tt.err {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.err)
+			}
+			if err == nil && !reflect.DeepEqual(got, want) {
+				t.Errorf("%v = %+v, want %+v", tt.name, got, want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "configfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "copier.yml")
+	if err := ioutil.WriteFile(name, []byte("defaults:\n  targetregion: us-west-2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load(name)
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if v := got.Defaults["targetregion"]; len(v) != 1 || v[0] != "us-west-2" {
+		t.Errorf("Load() targetregion = %v, want %v", v, "us-west-2")
+	}
+
+	if _, err := Load(filepath.Join(dir, "missing.yml")); err == nil {
+		t.Errorf("Load() missing file = nil, want error")
+	}
+}
@@ -0,0 +1,94 @@
+// Package events parses the RDS events sent when a snapshot is created, as delivered by EventBridge or an RDS
+// event subscription, directly or wrapped in an SNS notification.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Event is a snapshot having been created
+type Event struct {
+	Snapshot string // The snapshot identifier, e.g. rds:db-1-2019-03-30-00-05
+	Cluster  bool   // An Aurora cluster snapshot, rather than an rds instance snapshot
+	Region   string // Where the snapshot was created, "" if the event does not say
+}
+
+// envelope holds the fields of each of the formats an event may arrive in
+type envelope struct {
+	// SNS notification
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+
+	// EventBridge
+	Source     string `json:"source"`
+	DetailType string `json:"detail-type"`
+	Region     string `json:"region"`
+	Detail     struct {
+		SourceIdentifier string `json:"SourceIdentifier"`
+		SourceType       string `json:"SourceType"`
+		Message          string `json:"Message"`
+	} `json:"detail"`
+
+	// RDS event subscription
+	EventSource  string `json:"Event Source"`
+	SourceID     string `json:"Source ID"`
+	SourceARN    string `json:"Source ARN"`
+	EventMessage string `json:"Event Message"`
+}
+
+// Parse parses an event. It returns nil, and no error, for a well formed event which is not a snapshot being
+// created, e.g. a snapshot being deleted or an instance rebooting.
+func Parse(body []byte) (*Event, error) {
+	var e envelope
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, fmt.Errorf("invalid event: %v", err)
+	}
+
+	switch {
+	case e.Type == "Notification":
+		return Parse([]byte(e.Message))
+
+	case e.Source == "aws.rds":
+		if !created(e.Detail.Message) {
+			return nil, nil
+		}
+		switch {
+		case e.Detail.SourceType == "SNAPSHOT" || e.DetailType == "RDS DB Snapshot Event":
+			return &Event{Snapshot: e.Detail.SourceIdentifier, Region: e.Region}, nil
+		case e.Detail.SourceType == "CLUSTER_SNAPSHOT" || e.DetailType == "RDS DB Cluster Snapshot Event":
+			return &Event{Snapshot: e.Detail.SourceIdentifier, Cluster: true, Region: e.Region}, nil
+		}
+		return nil, nil
+
+	case e.EventSource != "":
+		if !created(e.EventMessage) {
+			return nil, nil
+		}
+		switch e.EventSource {
+		case "db-snapshot":
+			return &Event{Snapshot: e.SourceID, Region: region(e.SourceARN)}, nil
+		case "db-cluster-snapshot":
+			return &Event{Snapshot: e.SourceID, Cluster: true, Region: region(e.SourceARN)}, nil
+		}
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("invalid event: not an RDS event")
+}
+
+// created reports if an event message is that of a snapshot having been created, e.g. "Automated snapshot created".
+// Not "Creating automated snapshot", as it can not be copied until it is available.
+func created(message string) bool {
+	return strings.Contains(strings.ToLower(message), "snapshot created")
+}
+
+// region returns the region of an ARN, e.g. arn:aws:rds:ap-southeast-2:123456789012:snapshot:rds:db-1
+func region(arn string) string {
+	f := strings.Split(arn, ":")
+	if len(f) < 4 {
+		return ""
+	}
+	return f[3]
+}
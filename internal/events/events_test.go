@@ -0,0 +1,76 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		body    string
+		want    *Event
+		wantErr bool
+	}{
+		{
+			name: "Parse_eventbridge",
+			body: `{"version":"0","source":"aws.rds","detail-type":"RDS DB Snapshot Event","region":"ap-southeast-2",
+				"detail":{"EventCategories":["creation"],"SourceType":"SNAPSHOT","SourceIdentifier":"rds:db-1-2019-03-30-00-05","Message":"Automated snapshot created","EventID":"RDS-EVENT-0091"}}`,
+			want: &Event{Snapshot: "rds:db-1-2019-03-30-00-05", Region: "ap-southeast-2"},
+		},
+		{
+			name: "Parse_eventbridge_cluster",
+			body: `{"source":"aws.rds","detail-type":"RDS DB Cluster Snapshot Event","region":"ap-southeast-2",
+				"detail":{"SourceType":"CLUSTER_SNAPSHOT","SourceIdentifier":"aurora-1-nightly","Message":"Manual cluster snapshot created"}}`,
+			want: &Event{Snapshot: "aurora-1-nightly", Cluster: true, Region: "ap-southeast-2"},
+		},
+		{
+			name: "Parse_eventbridge_creating",
+			body: `{"source":"aws.rds","detail-type":"RDS DB Snapshot Event","detail":{"SourceType":"SNAPSHOT","SourceIdentifier":"rds:db-1","Message":"Creating automated snapshot"}}`,
+		},
+		{
+			name: "Parse_eventbridge_instance_event",
+			body: `{"source":"aws.rds","detail-type":"RDS DB Instance Event","detail":{"SourceType":"DB_INSTANCE","SourceIdentifier":"db-1","Message":"DB instance restarted"}}`,
+		},
+		{
+			name: "Parse_subscription_via_sns",
+			body: `{"Type":"Notification","MessageId":"1","Message":"{\"Event Source\":\"db-snapshot\",\"Source ID\":\"db-1-manual\",\"Source ARN\":\"arn:aws:rds:ap-southeast-2:123456789012:snapshot:db-1-manual\",\"Event Message\":\"Manual snapshot created\"}"}`,
+			want: &Event{Snapshot: "db-1-manual", Region: "ap-southeast-2"},
+		},
+		{
+			name: "Parse_eventbridge_via_sns",
+			body: `{"Type":"Notification","Message":"{\"source\":\"aws.rds\",\"detail\":{\"SourceType\":\"SNAPSHOT\",\"SourceIdentifier\":\"db-2-manual\",\"Message\":\"Manual snapshot created\"}}"}`,
+			want: &Event{Snapshot: "db-2-manual"},
+		},
+		{
+			name: "Parse_subscription_deleted",
+			body: `{"Event Source":"db-snapshot","Source ID":"db-1-manual","Event Message":"Deleted manual snapshot"}`,
+		},
+		{
+			name:    "Parse_not_rds",
+			body:    `{"source":"aws.ec2"}`,
+			wantErr: true,
+		},
+		{
+			name:    "Parse_not_json",
+			body:    `snapshot created`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%v = %+v, want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
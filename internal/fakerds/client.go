@@ -0,0 +1,412 @@
+package fakerds
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+)
+
+// Client is the RDS API of a single region of a Cloud. Operations it does not fake panic.
+type Client struct {
+	rdsiface.RDSAPI
+
+	cloud *Cloud
+	name  string
+}
+
+// begin locks the Cloud and records a call to operation, returning the state of the client's region, or an error if
+// ctx is done or one has been injected. The Cloud is locked unless an error is returned.
+func (c *Client) begin(ctx aws.Context, operation string) (*region, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, awserr.New(request.CanceledErrorCode, "request context canceled", err)
+	}
+	c.cloud.mu.Lock()
+	if err := c.cloud.call(c.name, operation); err != nil {
+		c.cloud.mu.Unlock()
+		return nil, err
+	}
+	return c.cloud.region(c.name), nil
+}
+
+// DescribeDBInstancesWithContext lists the rds instances in the region, or the one identified
+func (c *Client) DescribeDBInstancesWithContext(ctx aws.Context, input *rds.DescribeDBInstancesInput, _ ...request.Option) (*rds.DescribeDBInstancesOutput, error) {
+	r, err := c.begin(ctx, "DescribeDBInstances")
+	if err != nil {
+		return nil, err
+	}
+	defer c.cloud.mu.Unlock()
+
+	out := &rds.DescribeDBInstancesOutput{}
+	for _, i := range r.instances {
+		if input.DBInstanceIdentifier == nil || *input.DBInstanceIdentifier == *i.DBInstanceIdentifier {
+			cp := *i
+			out.DBInstances = append(out.DBInstances, &cp)
+		}
+	}
+	if input.DBInstanceIdentifier != nil && len(out.DBInstances) == 0 {
+		return nil, awserr.New(rds.ErrCodeDBInstanceNotFoundFault, fmt.Sprintf("DBInstance %s not found.", *input.DBInstanceIdentifier), nil)
+	}
+	return out, nil
+}
+
+// DescribeDBInstancesPagesWithContext lists the rds instances in the region, as a single page
+func (c *Client) DescribeDBInstancesPagesWithContext(ctx aws.Context, input *rds.DescribeDBInstancesInput, fn func(*rds.DescribeDBInstancesOutput, bool) bool, opts ...request.Option) error {
+	out, err := c.DescribeDBInstancesWithContext(ctx, input, opts...)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+// DescribeDBClustersWithContext lists the Aurora clusters in the region, or the one identified
+func (c *Client) DescribeDBClustersWithContext(ctx aws.Context, input *rds.DescribeDBClustersInput, _ ...request.Option) (*rds.DescribeDBClustersOutput, error) {
+	r, err := c.begin(ctx, "DescribeDBClusters")
+	if err != nil {
+		return nil, err
+	}
+	defer c.cloud.mu.Unlock()
+
+	out := &rds.DescribeDBClustersOutput{}
+	for _, cl := range r.clusters {
+		if input.DBClusterIdentifier == nil || *input.DBClusterIdentifier == *cl.DBClusterIdentifier {
+			cp := *cl
+			out.DBClusters = append(out.DBClusters, &cp)
+		}
+	}
+	if input.DBClusterIdentifier != nil && len(out.DBClusters) == 0 {
+		return nil, awserr.New(rds.ErrCodeDBClusterNotFoundFault, fmt.Sprintf("DBCluster %s not found.", *input.DBClusterIdentifier), nil)
+	}
+	return out, nil
+}
+
+// DescribeDBClustersPagesWithContext lists the Aurora clusters in the region, as a single page
+func (c *Client) DescribeDBClustersPagesWithContext(ctx aws.Context, input *rds.DescribeDBClustersInput, fn func(*rds.DescribeDBClustersOutput, bool) bool, opts ...request.Option) error {
+	out, err := c.DescribeDBClustersWithContext(ctx, input, opts...)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+// ListTagsForResourceWithContext returns the tags of an rds instance, Aurora cluster or snapshot
+func (c *Client) ListTagsForResourceWithContext(ctx aws.Context, input *rds.ListTagsForResourceInput, _ ...request.Option) (*rds.ListTagsForResourceOutput, error) {
+	r, err := c.begin(ctx, "ListTagsForResource")
+	if err != nil {
+		return nil, err
+	}
+	defer c.cloud.mu.Unlock()
+
+	return &rds.ListTagsForResourceOutput{TagList: r.tags[aws.StringValue(input.ResourceName)]}, nil
+}
+
+// RemoveTagsFromResourceWithContext removes tags, by key, from an rds instance, Aurora cluster or snapshot
+func (c *Client) RemoveTagsFromResourceWithContext(ctx aws.Context, input *rds.RemoveTagsFromResourceInput, _ ...request.Option) (*rds.RemoveTagsFromResourceOutput, error) {
+	r, err := c.begin(ctx, "RemoveTagsFromResource")
+	if err != nil {
+		return nil, err
+	}
+	defer c.cloud.mu.Unlock()
+
+	arn := aws.StringValue(input.ResourceName)
+	var kept []*rds.Tag
+	for _, t := range r.tags[arn] {
+		found := false
+		for _, k := range aws.StringValueSlice(input.TagKeys) {
+			found = found || aws.StringValue(t.Key) == k
+		}
+		if !found {
+			kept = append(kept, t)
+		}
+	}
+	r.tags[arn] = kept
+	return &rds.RemoveTagsFromResourceOutput{}, nil
+}
+
+// DescribeDBSnapshotsWithContext lists the snapshots in the region, moving copies in progress on a step first
+func (c *Client) DescribeDBSnapshotsWithContext(ctx aws.Context, input *rds.DescribeDBSnapshotsInput, _ ...request.Option) (*rds.DescribeDBSnapshotsOutput, error) {
+	r, err := c.begin(ctx, "DescribeDBSnapshots")
+	if err != nil {
+		return nil, err
+	}
+	defer c.cloud.mu.Unlock()
+	c.cloud.step(r)
+
+	out := &rds.DescribeDBSnapshotsOutput{}
+	for _, s := range r.snapshots {
+		if (input.DBSnapshotIdentifier != nil && *input.DBSnapshotIdentifier != *s.DBSnapshotIdentifier) ||
+			(input.DBInstanceIdentifier != nil && *input.DBInstanceIdentifier != *s.DBInstanceIdentifier) ||
+			(input.SnapshotType != nil && *input.SnapshotType != *s.SnapshotType) {
+			continue
+		}
+		cp := *s
+		out.DBSnapshots = append(out.DBSnapshots, &cp)
+	}
+	if input.DBSnapshotIdentifier != nil && len(out.DBSnapshots) == 0 {
+		return nil, awserr.New(rds.ErrCodeDBSnapshotNotFoundFault, fmt.Sprintf("DBSnapshot %s not found.", *input.DBSnapshotIdentifier), nil)
+	}
+	return out, nil
+}
+
+// DescribeDBSnapshotsPagesWithContext lists the snapshots in the region, as a single page
+func (c *Client) DescribeDBSnapshotsPagesWithContext(ctx aws.Context, input *rds.DescribeDBSnapshotsInput, fn func(*rds.DescribeDBSnapshotsOutput, bool) bool, opts ...request.Option) error {
+	out, err := c.DescribeDBSnapshotsWithContext(ctx, input, opts...)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+// DescribeDBClusterSnapshotsWithContext lists the Aurora cluster snapshots in the region, as a single page, moving
+// copies in progress on a step first
+func (c *Client) DescribeDBClusterSnapshotsWithContext(ctx aws.Context, input *rds.DescribeDBClusterSnapshotsInput, _ ...request.Option) (*rds.DescribeDBClusterSnapshotsOutput, error) {
+	r, err := c.begin(ctx, "DescribeDBClusterSnapshots")
+	if err != nil {
+		return nil, err
+	}
+	defer c.cloud.mu.Unlock()
+	c.cloud.step(r)
+
+	out := &rds.DescribeDBClusterSnapshotsOutput{}
+	for _, s := range r.clusterSnapshots {
+		if (input.DBClusterSnapshotIdentifier != nil && *input.DBClusterSnapshotIdentifier != *s.DBClusterSnapshotIdentifier) ||
+			(input.DBClusterIdentifier != nil && *input.DBClusterIdentifier != *s.DBClusterIdentifier) ||
+			(input.SnapshotType != nil && *input.SnapshotType != *s.SnapshotType) {
+			continue
+		}
+		cp := *s
+		out.DBClusterSnapshots = append(out.DBClusterSnapshots, &cp)
+	}
+	if input.DBClusterSnapshotIdentifier != nil && len(out.DBClusterSnapshots) == 0 {
+		return nil, awserr.New(rds.ErrCodeDBClusterSnapshotNotFoundFault, fmt.Sprintf("DBClusterSnapshot %s not found.", *input.DBClusterSnapshotIdentifier), nil)
+	}
+	return out, nil
+}
+
+// source returns the region and identifier of the snapshot a copy is made from, which is an ARN when in another region
+func (c *Client) source(id string) (string, string) {
+	if strings.HasPrefix(id, "arn:") {
+		return parseARN(id)
+	}
+	return c.name, id
+}
+
+// CopyDBSnapshotWithContext starts copying a snapshot into the region, the copy being "pending"
+func (c *Client) CopyDBSnapshotWithContext(ctx aws.Context, input *rds.CopyDBSnapshotInput, _ ...request.Option) (*rds.CopyDBSnapshotOutput, error) {
+	r, err := c.begin(ctx, "CopyDBSnapshot")
+	if err != nil {
+		return nil, err
+	}
+	defer c.cloud.mu.Unlock()
+
+	srcRegion, srcID := c.source(aws.StringValue(input.SourceDBSnapshotIdentifier))
+	var src *rds.DBSnapshot
+	for _, s := range c.cloud.region(srcRegion).snapshots {
+		if *s.DBSnapshotIdentifier == srcID {
+			src = s
+		}
+	}
+	if src == nil || aws.StringValue(src.Status) != "available" {
+		return nil, awserr.New(rds.ErrCodeDBSnapshotNotFoundFault, fmt.Sprintf("DBSnapshot %s not found.", aws.StringValue(input.SourceDBSnapshotIdentifier)), nil)
+	}
+	target := aws.StringValue(input.TargetDBSnapshotIdentifier)
+	for _, s := range r.snapshots {
+		if *s.DBSnapshotIdentifier == target {
+			return nil, awserr.New(rds.ErrCodeDBSnapshotAlreadyExistsFault, fmt.Sprintf("Cannot create the snapshot because a snapshot with the identifier %s already exists.", target), nil)
+		}
+	}
+
+	cp := &rds.DBSnapshot{
+		DBInstanceIdentifier:       src.DBInstanceIdentifier,
+		DBSnapshotIdentifier:       aws.String(target),
+		DBSnapshotArn:              aws.String(ARN(c.name, "snapshot", target)),
+		SnapshotType:               aws.String("manual"),
+		SnapshotCreateTime:         src.SnapshotCreateTime,
+		Status:                     aws.String("pending"),
+		PercentProgress:            aws.Int64(0),
+		AllocatedStorage:           src.AllocatedStorage,
+		SourceDBSnapshotIdentifier: src.DBSnapshotArn,
+		KmsKeyId:                   input.KmsKeyId,
+	}
+	if srcRegion != c.name {
+		cp.SourceRegion = aws.String(srcRegion)
+	}
+	r.snapshots = append(r.snapshots, cp)
+	r.tags[*cp.DBSnapshotArn] = append([]*rds.Tag(nil), input.Tags...)
+	out := *cp
+	return &rds.CopyDBSnapshotOutput{DBSnapshot: &out}, nil
+}
+
+// CopyDBClusterSnapshotWithContext starts copying an Aurora cluster snapshot into the region, the copy being "pending"
+func (c *Client) CopyDBClusterSnapshotWithContext(ctx aws.Context, input *rds.CopyDBClusterSnapshotInput, _ ...request.Option) (*rds.CopyDBClusterSnapshotOutput, error) {
+	r, err := c.begin(ctx, "CopyDBClusterSnapshot")
+	if err != nil {
+		return nil, err
+	}
+	defer c.cloud.mu.Unlock()
+
+	srcRegion, srcID := c.source(aws.StringValue(input.SourceDBClusterSnapshotIdentifier))
+	var src *rds.DBClusterSnapshot
+	for _, s := range c.cloud.region(srcRegion).clusterSnapshots {
+		if *s.DBClusterSnapshotIdentifier == srcID {
+			src = s
+		}
+	}
+	if src == nil || aws.StringValue(src.Status) != "available" {
+		return nil, awserr.New(rds.ErrCodeDBClusterSnapshotNotFoundFault, fmt.Sprintf("DBClusterSnapshot %s not found.", aws.StringValue(input.SourceDBClusterSnapshotIdentifier)), nil)
+	}
+	target := aws.StringValue(input.TargetDBClusterSnapshotIdentifier)
+	for _, s := range r.clusterSnapshots {
+		if *s.DBClusterSnapshotIdentifier == target {
+			return nil, awserr.New(rds.ErrCodeDBClusterSnapshotAlreadyExistsFault, fmt.Sprintf("Cannot create the cluster snapshot because one with the identifier %s already exists.", target), nil)
+		}
+	}
+
+	cp := &rds.DBClusterSnapshot{
+		DBClusterIdentifier:         src.DBClusterIdentifier,
+		DBClusterSnapshotIdentifier: aws.String(target),
+		DBClusterSnapshotArn:        aws.String(ARN(c.name, "cluster-snapshot", target)),
+		SnapshotType:                aws.String("manual"),
+		SnapshotCreateTime:          src.SnapshotCreateTime,
+		Status:                      aws.String("pending"),
+		PercentProgress:             aws.Int64(0),
+		AllocatedStorage:            src.AllocatedStorage,
+		SourceDBClusterSnapshotArn:  src.DBClusterSnapshotArn,
+		KmsKeyId:                    input.KmsKeyId,
+	}
+	r.clusterSnapshots = append(r.clusterSnapshots, cp)
+	r.tags[*cp.DBClusterSnapshotArn] = append([]*rds.Tag(nil), input.Tags...)
+	out := *cp
+	return &rds.CopyDBClusterSnapshotOutput{DBClusterSnapshot: &out}, nil
+}
+
+// CopyDBSnapshotRequest builds a CopyDBSnapshot request for the region, to be presigned. It is not sent.
+func (c *Client) CopyDBSnapshotRequest(input *rds.CopyDBSnapshotInput) (*request.Request, *rds.CopyDBSnapshotOutput) {
+	return presigner(c.name).CopyDBSnapshotRequest(input)
+}
+
+// CopyDBClusterSnapshotRequest builds a CopyDBClusterSnapshot request for the region, to be presigned. It is not sent.
+func (c *Client) CopyDBClusterSnapshotRequest(input *rds.CopyDBClusterSnapshotInput) (*request.Request, *rds.CopyDBClusterSnapshotOutput) {
+	return presigner(c.name).CopyDBClusterSnapshotRequest(input)
+}
+
+// DeleteDBSnapshotWithContext deletes a snapshot, cancelling it if it is a copy in progress
+func (c *Client) DeleteDBSnapshotWithContext(ctx aws.Context, input *rds.DeleteDBSnapshotInput, _ ...request.Option) (*rds.DeleteDBSnapshotOutput, error) {
+	r, err := c.begin(ctx, "DeleteDBSnapshot")
+	if err != nil {
+		return nil, err
+	}
+	defer c.cloud.mu.Unlock()
+
+	id := aws.StringValue(input.DBSnapshotIdentifier)
+	for i, s := range r.snapshots {
+		if *s.DBSnapshotIdentifier == id {
+			r.snapshots = append(r.snapshots[:i:i], r.snapshots[i+1:]...)
+			delete(r.tags, aws.StringValue(s.DBSnapshotArn))
+			delete(r.steps, id)
+			delete(r.shared, id)
+			out := *s
+			out.Status = aws.String("deleted")
+			return &rds.DeleteDBSnapshotOutput{DBSnapshot: &out}, nil
+		}
+	}
+	return nil, awserr.New(rds.ErrCodeDBSnapshotNotFoundFault, fmt.Sprintf("DBSnapshot %s not found.", id), nil)
+}
+
+// DeleteDBClusterSnapshotWithContext deletes an Aurora cluster snapshot, cancelling it if it is a copy in progress
+func (c *Client) DeleteDBClusterSnapshotWithContext(ctx aws.Context, input *rds.DeleteDBClusterSnapshotInput, _ ...request.Option) (*rds.DeleteDBClusterSnapshotOutput, error) {
+	r, err := c.begin(ctx, "DeleteDBClusterSnapshot")
+	if err != nil {
+		return nil, err
+	}
+	defer c.cloud.mu.Unlock()
+
+	id := aws.StringValue(input.DBClusterSnapshotIdentifier)
+	for i, s := range r.clusterSnapshots {
+		if *s.DBClusterSnapshotIdentifier == id {
+			r.clusterSnapshots = append(r.clusterSnapshots[:i:i], r.clusterSnapshots[i+1:]...)
+			delete(r.tags, aws.StringValue(s.DBClusterSnapshotArn))
+			delete(r.steps, id)
+			delete(r.shared, id)
+			out := *s
+			out.Status = aws.String("deleted")
+			return &rds.DeleteDBClusterSnapshotOutput{DBClusterSnapshot: &out}, nil
+		}
+	}
+	return nil, awserr.New(rds.ErrCodeDBClusterSnapshotNotFoundFault, fmt.Sprintf("DBClusterSnapshot %s not found.", id), nil)
+}
+
+// share adds and removes the accounts a snapshot is shared with. c.cloud.mu must be held.
+func share(r *region, id string, add, remove []*string) {
+	accounts := r.shared[id]
+	for _, a := range aws.StringValueSlice(remove) {
+		for i, s := range accounts {
+			if s == a {
+				accounts = append(accounts[:i:i], accounts[i+1:]...)
+				break
+			}
+		}
+	}
+	for _, a := range aws.StringValueSlice(add) {
+		found := false
+		for _, s := range accounts {
+			found = found || s == a
+		}
+		if !found {
+			accounts = append(accounts, a)
+		}
+	}
+	r.shared[id] = accounts
+}
+
+// ModifyDBSnapshotAttributeWithContext shares a snapshot with, or stops sharing it with, other accounts
+func (c *Client) ModifyDBSnapshotAttributeWithContext(ctx aws.Context, input *rds.ModifyDBSnapshotAttributeInput, _ ...request.Option) (*rds.ModifyDBSnapshotAttributeOutput, error) {
+	r, err := c.begin(ctx, "ModifyDBSnapshotAttribute")
+	if err != nil {
+		return nil, err
+	}
+	defer c.cloud.mu.Unlock()
+
+	id := aws.StringValue(input.DBSnapshotIdentifier)
+	for _, s := range r.snapshots {
+		if *s.DBSnapshotIdentifier != id {
+			continue
+		}
+		if aws.StringValue(s.SnapshotType) != "manual" {
+			return nil, awserr.New(rds.ErrCodeInvalidDBSnapshotStateFault, "Only manual snapshots may be shared.", nil)
+		}
+		share(r, id, input.ValuesToAdd, input.ValuesToRemove)
+		return &rds.ModifyDBSnapshotAttributeOutput{}, nil
+	}
+	return nil, awserr.New(rds.ErrCodeDBSnapshotNotFoundFault, fmt.Sprintf("DBSnapshot %s not found.", id), nil)
+}
+
+// ModifyDBClusterSnapshotAttributeWithContext shares an Aurora cluster snapshot with, or stops sharing it with, other
+// accounts
+func (c *Client) ModifyDBClusterSnapshotAttributeWithContext(ctx aws.Context, input *rds.ModifyDBClusterSnapshotAttributeInput, _ ...request.Option) (*rds.ModifyDBClusterSnapshotAttributeOutput, error) {
+	r, err := c.begin(ctx, "ModifyDBClusterSnapshotAttribute")
+	if err != nil {
+		return nil, err
+	}
+	defer c.cloud.mu.Unlock()
+
+	id := aws.StringValue(input.DBClusterSnapshotIdentifier)
+	for _, s := range r.clusterSnapshots {
+		if *s.DBClusterSnapshotIdentifier != id {
+			continue
+		}
+		if aws.StringValue(s.SnapshotType) != "manual" {
+			return nil, awserr.New(rds.ErrCodeInvalidDBClusterSnapshotStateFault, "Only manual cluster snapshots may be shared.", nil)
+		}
+		share(r, id, input.ValuesToAdd, input.ValuesToRemove)
+		return &rds.ModifyDBClusterSnapshotAttributeOutput{}, nil
+	}
+	return nil, awserr.New(rds.ErrCodeDBClusterSnapshotNotFoundFault, fmt.Sprintf("DBClusterSnapshot %s not found.", id), nil)
+}
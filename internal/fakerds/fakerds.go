@@ -0,0 +1,295 @@
+// Package fakerds is an in memory, stateful stand-in for the AWS RDS API, across regions, for tests.
+//
+// A Cloud holds the rds instances, Aurora clusters, their tags and snapshots in each region; Cloud.Region returns
+// an rdsiface.RDSAPI for one of them. Copies made with CopyDBSnapshot or CopyDBClusterSnapshot start "pending",
+// then move through "copying" to "available" (or "failed", see FailCopy) one step each time the snapshots of their
+// region are described. Errors, e.g. throttling, can be injected into any operation with Fail.
+package fakerds
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// Account is the AWS account ID of the fake
+const Account = "123456789012"
+
+// Cloud is the state of the fake, shared by the Clients of each region. It is safe for concurrent use.
+type Cloud struct {
+	// Steps is the number of times its region is described before a copy finishes, 3 if not set
+	Steps int
+
+	mu      sync.Mutex
+	regions map[string]*region
+	faults  []*fault
+	calls   map[string]int
+}
+
+// region is the state of a single region
+type region struct {
+	instances        []*rds.DBInstance
+	clusters         []*rds.DBCluster
+	tags             map[string][]*rds.Tag // By ARN
+	snapshots        []*rds.DBSnapshot
+	clusterSnapshots []*rds.DBClusterSnapshot
+	steps            map[string]int      // Steps taken by each copy in progress, by snapshot identifier
+	fail             map[string]bool     // Copies which will fail, by target snapshot identifier
+	shared           map[string][]string // Accounts each snapshot is shared with, by snapshot identifier
+}
+
+// fault is an error returned by the next times calls to an operation in a region
+type fault struct {
+	region    string
+	operation string
+	err       error
+	times     int
+}
+
+// New returns an empty Cloud
+func New() *Cloud {
+	return &Cloud{regions: make(map[string]*region), calls: make(map[string]int)}
+}
+
+// region returns the state of a region, creating it if need be. c.mu must be held.
+func (c *Cloud) region(name string) *region {
+	r, ok := c.regions[name]
+	if !ok {
+		r = &region{tags: make(map[string][]*rds.Tag), steps: make(map[string]int), fail: make(map[string]bool), shared: make(map[string][]string)}
+		c.regions[name] = r
+	}
+	return r
+}
+
+// call records a call to an operation in a region, returning the error injected for it, if any. c.mu must be held.
+func (c *Cloud) call(region, operation string) error {
+	c.calls[region+"/"+operation]++
+	for i, f := range c.faults {
+		if f.region == region && f.operation == operation {
+			f.times--
+			if f.times <= 0 {
+				c.faults = append(c.faults[:i], c.faults[i+1:]...)
+			}
+			return f.err
+		}
+	}
+	return nil
+}
+
+// Fail makes the next times calls to operation, e.g. "CopyDBSnapshot", in region return err
+func (c *Cloud) Fail(region, operation string, err error, times int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.faults = append(c.faults, &fault{region: region, operation: operation, err: err, times: times})
+}
+
+// Throttle makes the next times calls to operation in region fail, as AWS does when its rate limit is exceeded
+func (c *Cloud) Throttle(region, operation string, times int) {
+	c.Fail(region, operation, awserr.New("Throttling", "Rate exceeded", nil), times)
+}
+
+// FailCopy makes the copy to the target snapshot in region fail, rather than become available
+func (c *Cloud) FailCopy(region, target string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.region(region).fail[target] = true
+}
+
+// Calls returns the number of calls made to an operation in a region
+func (c *Cloud) Calls(region, operation string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[region+"/"+operation]
+}
+
+// ARN returns the ARN of a resource, e.g. ARN("us-west-2", "snapshot", "db-1-copy")
+func ARN(region, kind, id string) string {
+	return fmt.Sprintf("arn:aws:rds:%s:%s:%s:%s", region, Account, kind, id)
+}
+
+// parseARN returns the region and identifier of an ARN, which may itself contain ":", e.g. rds:db-1-2019-03-30
+func parseARN(arn string) (string, string) {
+	f := strings.SplitN(arn, ":", 7)
+	if len(f) < 7 {
+		return "", arn
+	}
+	return f[3], f[6]
+}
+
+// tagList converts tags for the API
+func tagList(tags map[string]string) []*rds.Tag {
+	var l []*rds.Tag
+	for k, v := range tags {
+		l = append(l, &rds.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return l
+}
+
+// AddInstance adds an available rds instance to a region
+func (c *Cloud) AddInstance(region, id string, tags map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := c.region(region)
+	arn := ARN(region, "db", id)
+	r.instances = append(r.instances, &rds.DBInstance{
+		DBInstanceIdentifier: aws.String(id),
+		DBInstanceArn:        aws.String(arn),
+		DBInstanceStatus:     aws.String("available"),
+	})
+	r.tags[arn] = tagList(tags)
+}
+
+// AddCluster adds an available Aurora cluster to a region
+func (c *Cloud) AddCluster(region, id string, tags map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := c.region(region)
+	arn := ARN(region, "cluster", id)
+	r.clusters = append(r.clusters, &rds.DBCluster{
+		DBClusterIdentifier: aws.String(id),
+		DBClusterArn:        aws.String(arn),
+		Status:              aws.String("available"),
+	})
+	r.tags[arn] = tagList(tags)
+}
+
+// AddSnapshot adds an available snapshot of an rds instance to a region. Identifiers starting "rds:" are automated
+// snapshots, the rest manual.
+func (c *Cloud) AddSnapshot(region, instance, id string, created time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := c.region(region)
+	r.snapshots = append(r.snapshots, &rds.DBSnapshot{
+		DBInstanceIdentifier: aws.String(instance),
+		DBSnapshotIdentifier: aws.String(id),
+		DBSnapshotArn:        aws.String(ARN(region, "snapshot", id)),
+		SnapshotType:         aws.String(snapshotType(id)),
+		SnapshotCreateTime:   aws.Time(created),
+		Status:               aws.String("available"),
+		PercentProgress:      aws.Int64(100),
+		AllocatedStorage:     aws.Int64(20),
+	})
+}
+
+// AddClusterSnapshot adds an available snapshot of an Aurora cluster to a region
+func (c *Cloud) AddClusterSnapshot(region, cluster, id string, created time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := c.region(region)
+	r.clusterSnapshots = append(r.clusterSnapshots, &rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String(cluster),
+		DBClusterSnapshotIdentifier: aws.String(id),
+		DBClusterSnapshotArn:        aws.String(ARN(region, "cluster-snapshot", id)),
+		SnapshotType:                aws.String(snapshotType(id)),
+		SnapshotCreateTime:          aws.Time(created),
+		Status:                      aws.String("available"),
+		PercentProgress:             aws.Int64(100),
+		AllocatedStorage:            aws.Int64(20),
+	})
+}
+
+func snapshotType(id string) string {
+	if strings.HasPrefix(id, "rds:") {
+		return "automated"
+	}
+	return "manual"
+}
+
+// Snapshots returns the rds instance snapshots in a region
+func (c *Cloud) Snapshots(region string) []*rds.DBSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var res []*rds.DBSnapshot
+	for _, s := range c.region(region).snapshots {
+		cp := *s
+		res = append(res, &cp)
+	}
+	return res
+}
+
+// ClusterSnapshots returns the Aurora cluster snapshots in a region
+func (c *Cloud) ClusterSnapshots(region string) []*rds.DBClusterSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var res []*rds.DBClusterSnapshot
+	for _, s := range c.region(region).clusterSnapshots {
+		cp := *s
+		res = append(res, &cp)
+	}
+	return res
+}
+
+// Tags returns the tags of a resource, by ARN, by key
+func (c *Cloud) Tags(region, arn string) map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tags := make(map[string]string)
+	for _, t := range c.region(region).tags[arn] {
+		tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+	}
+	return tags
+}
+
+// Shared returns the accounts a snapshot in a region is shared with
+func (c *Cloud) Shared(region, id string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.region(region).shared[id]...)
+}
+
+// step moves each copy in progress in a region on a step. c.mu must be held.
+func (c *Cloud) step(r *region) {
+	steps := c.Steps
+	if steps <= 0 {
+		steps = 3
+	}
+	advance := func(id string, status **string, progress **int64) {
+		if *status == nil || (**status != "pending" && **status != "copying") {
+			return
+		}
+		r.steps[id]++
+		n := r.steps[id]
+		switch {
+		case n >= steps && r.fail[id]:
+			*status = aws.String("failed")
+		case n >= steps:
+			*status, *progress = aws.String("available"), aws.Int64(100)
+		default:
+			*status, *progress = aws.String("copying"), aws.Int64(int64(100*n/steps))
+		}
+	}
+	// Snapshots are replaced rather than changed, as callers may still hold those returned earlier
+	for i, s := range r.snapshots {
+		cp := *s
+		advance(*s.DBSnapshotIdentifier, &cp.Status, &cp.PercentProgress)
+		r.snapshots[i] = &cp
+	}
+	for i, s := range r.clusterSnapshots {
+		cp := *s
+		advance(*s.DBClusterSnapshotIdentifier, &cp.Status, &cp.PercentProgress)
+		r.clusterSnapshots[i] = &cp
+	}
+}
+
+// Region returns a client for a region of the Cloud
+func (c *Cloud) Region(name string) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.region(name)
+	return &Client{cloud: c, name: name}
+}
+
+// presigner builds the requests presigned for encrypted copies. Presigning is done locally, so needs no AWS access.
+func presigner(region string) *rds.RDS {
+	return rds.New(session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String(region),
+		Credentials: credentials.NewStaticCredentials("fake", "fake", ""),
+	})))
+}
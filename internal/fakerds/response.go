@@ -0,0 +1,105 @@
+package fakerds
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// The results of the Query API actions served, shaped as the RDS API returns them, for encoding/xml
+
+type instancesResult struct {
+	DBInstances []instanceXML `xml:"DBInstances>DBInstance"`
+}
+
+type tagsResult struct {
+	TagList []tagXML `xml:"TagList>Tag"`
+}
+
+type snapshotsResult struct {
+	DBSnapshots []snapshotXML `xml:"DBSnapshots>DBSnapshot"`
+}
+
+type snapshotResult struct {
+	DBSnapshot snapshotXML
+}
+
+type emptyResult struct{}
+
+type instanceXML struct {
+	DBInstanceIdentifier *string
+	DBInstanceArn        *string
+	DBInstanceStatus     *string
+}
+
+type tagXML struct {
+	Key   *string
+	Value *string
+}
+
+type snapshotXML struct {
+	DBSnapshotIdentifier       *string
+	DBSnapshotArn              *string
+	DBInstanceIdentifier       *string
+	SnapshotType               *string
+	SnapshotCreateTime         *string
+	Status                     *string
+	PercentProgress            *int64
+	AllocatedStorage           *int64
+	KmsKeyId                   *string
+	SourceDBSnapshotIdentifier *string
+	SourceRegion               *string
+}
+
+func instancesOf(all []*rds.DBInstance) instancesResult {
+	var r instancesResult
+	for _, i := range all {
+		r.DBInstances = append(r.DBInstances, instanceXML{
+			DBInstanceIdentifier: i.DBInstanceIdentifier,
+			DBInstanceArn:        i.DBInstanceArn,
+			DBInstanceStatus:     i.DBInstanceStatus,
+		})
+	}
+	return r
+}
+
+func tagsOf(all []*rds.Tag) tagsResult {
+	var r tagsResult
+	for _, t := range all {
+		r.TagList = append(r.TagList, tagXML{Key: t.Key, Value: t.Value})
+	}
+	return r
+}
+
+func snapshotsOf(all []*rds.DBSnapshot) snapshotsResult {
+	var r snapshotsResult
+	for _, s := range all {
+		r.DBSnapshots = append(r.DBSnapshots, snapshotOf(s))
+	}
+	return r
+}
+
+func snapshotOf(s *rds.DBSnapshot) snapshotXML {
+	return snapshotXML{
+		DBSnapshotIdentifier:       s.DBSnapshotIdentifier,
+		DBSnapshotArn:              s.DBSnapshotArn,
+		DBInstanceIdentifier:       s.DBInstanceIdentifier,
+		SnapshotType:               s.SnapshotType,
+		SnapshotCreateTime:         timestamp(s.SnapshotCreateTime),
+		Status:                     s.Status,
+		PercentProgress:            s.PercentProgress,
+		AllocatedStorage:           s.AllocatedStorage,
+		KmsKeyId:                   s.KmsKeyId,
+		SourceDBSnapshotIdentifier: s.SourceDBSnapshotIdentifier,
+		SourceRegion:               s.SourceRegion,
+	}
+}
+
+// timestamp formats a time as the RDS API does, in UTC to the millisecond, e.g. "2019-03-30T00:05:12.345Z"
+func timestamp(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.UTC().Format("2006-01-02T15:04:05.000Z")
+	return &s
+}
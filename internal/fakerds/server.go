@@ -0,0 +1,199 @@
+package fakerds
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// namespace is that of the RDS API version the SDK speaks
+const namespace = "http://rds.amazonaws.com/doc/2014-10-31/"
+
+// credentialScope matches the region of a signed request, e.g. "Credential=AKID/20190330/us-west-2/rds/aws4_request"
+var credentialScope = regexp.MustCompile(`Credential=[^/]+/[0-9]+/([a-z0-9-]+)/rds/`)
+
+// action is a Query API action, run against a region's Client. It returns the action's result, see response.go
+type action func(ctx context.Context, c *Client, param func(string) *string) (interface{}, error)
+
+// actions are the Query API actions served; those this tool uses for rds instances
+var actions = map[string]action{
+	"DescribeDBInstances": func(ctx context.Context, c *Client, param func(string) *string) (interface{}, error) {
+		out, err := c.DescribeDBInstancesWithContext(ctx, &rds.DescribeDBInstancesInput{DBInstanceIdentifier: param("DBInstanceIdentifier")})
+		if err != nil {
+			return nil, err
+		}
+		return instancesOf(out.DBInstances), nil
+	},
+	"ListTagsForResource": func(ctx context.Context, c *Client, param func(string) *string) (interface{}, error) {
+		out, err := c.ListTagsForResourceWithContext(ctx, &rds.ListTagsForResourceInput{ResourceName: param("ResourceName")})
+		if err != nil {
+			return nil, err
+		}
+		return tagsOf(out.TagList), nil
+	},
+	"DescribeDBSnapshots": func(ctx context.Context, c *Client, param func(string) *string) (interface{}, error) {
+		out, err := c.DescribeDBSnapshotsWithContext(ctx, &rds.DescribeDBSnapshotsInput{
+			DBInstanceIdentifier: param("DBInstanceIdentifier"),
+			DBSnapshotIdentifier: param("DBSnapshotIdentifier"),
+			SnapshotType:         param("SnapshotType"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return snapshotsOf(out.DBSnapshots), nil
+	},
+	"CopyDBSnapshot": func(ctx context.Context, c *Client, param func(string) *string) (interface{}, error) {
+		out, err := c.CopyDBSnapshotWithContext(ctx, &rds.CopyDBSnapshotInput{
+			SourceDBSnapshotIdentifier: param("SourceDBSnapshotIdentifier"),
+			TargetDBSnapshotIdentifier: param("TargetDBSnapshotIdentifier"),
+			KmsKeyId:                   param("KmsKeyId"),
+			Tags:                       tagParams(param),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return snapshotResult{snapshotOf(out.DBSnapshot)}, nil
+	},
+	"RemoveTagsFromResource": func(ctx context.Context, c *Client, param func(string) *string) (interface{}, error) {
+		var keys []*string
+		for i := 1; param(fmt.Sprintf("TagKeys.member.%d", i)) != nil; i++ {
+			keys = append(keys, param(fmt.Sprintf("TagKeys.member.%d", i)))
+		}
+		_, err := c.RemoveTagsFromResourceWithContext(ctx, &rds.RemoveTagsFromResourceInput{ResourceName: param("ResourceName"), TagKeys: keys})
+		return emptyResult{}, err
+	},
+	"DeleteDBSnapshot": func(ctx context.Context, c *Client, param func(string) *string) (interface{}, error) {
+		out, err := c.DeleteDBSnapshotWithContext(ctx, &rds.DeleteDBSnapshotInput{DBSnapshotIdentifier: param("DBSnapshotIdentifier")})
+		if err != nil {
+			return nil, err
+		}
+		return snapshotResult{snapshotOf(out.DBSnapshot)}, nil
+	},
+}
+
+// tagParams returns the Tags list parameter of a Query API request
+func tagParams(param func(string) *string) []*rds.Tag {
+	var tags []*rds.Tag
+	for i := 1; param(fmt.Sprintf("Tags.Tag.%d.Key", i)) != nil; i++ {
+		tags = append(tags, &rds.Tag{Key: param(fmt.Sprintf("Tags.Tag.%d.Key", i)), Value: param(fmt.Sprintf("Tags.Tag.%d.Value", i))})
+	}
+	return tags
+}
+
+// Handler serves the Cloud as the RDS Query API, for the SDK, or this tool, to be pointed at, e.g. with
+// aws.Config.Endpoint. The region is that the request was signed for, so one Handler serves every region.
+// Results are never paged.
+func Handler(c *Cloud) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			writeError(w, http.StatusBadRequest, awserr.New("MalformedQueryString", err.Error(), nil))
+			return
+		}
+		name := r.Form.Get("Action")
+		act, ok := actions[name]
+		if !ok {
+			writeError(w, http.StatusBadRequest, awserr.New("InvalidAction", fmt.Sprintf("The action %s is not valid for this web service.", name), nil))
+			return
+		}
+		m := credentialScope.FindStringSubmatch(r.Header.Get("Authorization"))
+		if m == nil {
+			writeError(w, http.StatusForbidden, awserr.New("MissingAuthenticationToken", "Request is missing a signature with a credential scope.", nil))
+			return
+		}
+
+		param := func(k string) *string {
+			if v, ok := r.Form[k]; ok && len(v) > 0 {
+				return aws.String(v[0])
+			}
+			return nil
+		}
+		out, err := act(r.Context(), c.Region(m[1]), param)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		var body bytes.Buffer
+		fmt.Fprintf(&body, `<%sResponse xmlns="%s">`, name, namespace)
+		if err := xml.NewEncoder(&body).EncodeElement(out, xml.StartElement{Name: xml.Name{Local: name + "Result"}}); err != nil {
+			writeError(w, http.StatusInternalServerError, awserr.New("InternalFailure", err.Error(), nil))
+			return
+		}
+		fmt.Fprintf(&body, `<ResponseMetadata><RequestId>%s</RequestId></ResponseMetadata></%sResponse>`, requestID(), name)
+		w.Header().Set("Content-Type", "text/xml")
+		w.Write(body.Bytes())
+	})
+}
+
+// writeError writes an AWS Query API error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	code, msg := "InternalFailure", err.Error()
+	if aerr, ok := err.(awserr.Error); ok {
+		code, msg = aerr.Code(), aerr.Message()
+	}
+	switch code {
+	case rds.ErrCodeDBInstanceNotFoundFault, rds.ErrCodeDBSnapshotNotFoundFault:
+		status = http.StatusNotFound
+	case request.CanceledErrorCode:
+		status = http.StatusServiceUnavailable
+	}
+	var b bytes.Buffer
+	b.WriteString(`<ErrorResponse xmlns="` + namespace + `"><Error><Type>Sender</Type><Code>`)
+	xml.EscapeText(&b, []byte(code))
+	b.WriteString(`</Code><Message>`)
+	xml.EscapeText(&b, []byte(msg))
+	b.WriteString(`</Message></Error><RequestId>` + requestID() + `</RequestId></ErrorResponse>`)
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(status)
+	w.Write(b.Bytes())
+}
+
+// requestID returns an identifier for a response
+func requestID() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// Seed is the initial state of a Cloud, by region, as JSON, e.g.
+//
+//	{"ap-southeast-2": {"instances": [{"id": "db-1", "tags": {"copy": "true"}}],
+//	                    "snapshots": [{"instance": "db-1", "id": "rds:db-1-2019-03-30", "created": "2019-03-30T00:00:00Z"}]}}
+type Seed map[string]struct {
+	Instances []struct {
+		ID   string            `json:"id"`
+		Tags map[string]string `json:"tags"`
+	} `json:"instances"`
+	Snapshots []struct {
+		Instance string    `json:"instance"`
+		ID       string    `json:"id"`
+		Created  time.Time `json:"created"`
+	} `json:"snapshots"`
+}
+
+// Load adds the rds instances and snapshots of a JSON Seed to the Cloud
+func (c *Cloud) Load(r io.Reader) error {
+	var seed Seed
+	if err := json.NewDecoder(r).Decode(&seed); err != nil {
+		return fmt.Errorf("invalid seed: %v", err)
+	}
+	for region, s := range seed {
+		for _, i := range s.Instances {
+			c.AddInstance(region, i.ID, i.Tags)
+		}
+		for _, snap := range s.Snapshots {
+			c.AddSnapshot(region, snap.Instance, snap.ID, snap.Created)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package fakerds
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/rdsops"
+	"github.com/bluebenno/rds-snapshot-copier/internal/snapops"
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	c := New()
+	c.Steps = 2
+	err := c.Load(strings.NewReader(`{"ap-southeast-2": {
+		"instances": [{"id": "db-1", "tags": {"copy": "true"}}],
+		"snapshots": [{"instance": "db-1", "id": "rds:db-1-2019-03-30", "created": "2019-03-30T00:00:00Z"}]}}`))
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	srv := httptest.NewServer(Handler(c))
+	defer srv.Close()
+
+	ctx := context.Background()
+	cfg := &wiring.Config{SourceRegion: "ap-southeast-2", TargetRegion: "us-west-2", SnapshotType: "instance",
+		Endpoints: map[string]string{"*": srv.URL}, StaticKeyID: "fake", StaticSecret: "fake"}
+	source, err := wiring.Session(cfg, "ap-southeast-2", "")
+	if err != nil {
+		t.Fatalf("Session() = %v", err)
+	}
+	target, err := wiring.Session(cfg, "us-west-2", "")
+	if err != nil {
+		t.Fatalf("Session() = %v", err)
+	}
+
+	instances, err := rdsops.List(ctx, source)
+	if err != nil || len(instances) != 1 || *instances[0].DBInstanceIdentifier != "db-1" {
+		t.Fatalf("rdsops.List() = %v, %v, want db-1", instances, err)
+	}
+	tag, err := rdsops.GetTag(ctx, source, *instances[0].DBInstanceArn, "copy")
+	if tag != "true" || err != nil {
+		t.Errorf("rdsops.GetTag() = %v, %v, want %v", tag, err, "true")
+	}
+	if i, err := rdsops.Get(ctx, target, "db-1"); i != nil || err != nil {
+		t.Errorf("rdsops.Get() = %v, %v, want nil", i, err)
+	}
+
+	snaps, err := snapops.List(ctx, source, "db-1")
+	if err != nil || len(snaps) != 1 {
+		t.Fatalf("snapops.List() = %v, %v, want 1 snapshot", snaps, err)
+	}
+	if want := time.Date(2019, 3, 30, 0, 0, 0, 0, time.UTC); !snaps[0].SnapshotCreateTime.Equal(want) {
+		t.Errorf("SnapshotCreateTime = %v, want %v", snaps[0].SnapshotCreateTime, want)
+	}
+
+	tags := []*rds.Tag{{Key: aws.String(snapops.SourceTag), Value: aws.String("rds:db-1-2019-03-30")}}
+	if _, err := snapops.PullSnapShot(ctx, cfg, target, snaps[0].DBSnapshotArn, "db-1-2019-03-30-cf-ap-southeast-2", tags); err != nil {
+		t.Fatalf("snapops.PullSnapShot() = %v", err)
+	}
+	copyARN := ARN("us-west-2", "snapshot", "db-1-2019-03-30-cf-ap-southeast-2")
+	if got, err := snapops.Tags(ctx, target, copyARN); err != nil || got[snapops.SourceTag] != "rds:db-1-2019-03-30" {
+		t.Errorf("snapops.Tags() = %v, %v, want the source tag", got, err)
+	}
+	if err := snapops.Untag(ctx, target, copyARN); err != nil {
+		t.Errorf("snapops.Untag() = %v", err)
+	}
+	if got, err := snapops.Tags(ctx, target, copyARN); err != nil || len(got) != 0 {
+		t.Errorf("snapops.Tags() after Untag = %v, %v, want none", got, err)
+	}
+	_, err = snapops.PullSnapShot(ctx, cfg, target, snaps[0].DBSnapshotArn, "db-1-2019-03-30-cf-ap-southeast-2", nil)
+	if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != rds.ErrCodeDBSnapshotAlreadyExistsFault {
+		t.Errorf("snapops.PullSnapShot() again = %v, want %v", err, rds.ErrCodeDBSnapshotAlreadyExistsFault)
+	}
+	for _, want := range []string{"copying", "available"} {
+		s, err := snapops.Describe(ctx, target, "db-1-2019-03-30-cf-ap-southeast-2")
+		if err != nil || s == nil || *s.Status != want {
+			t.Fatalf("snapops.Describe() = %v, %v, want %v", s, err, want)
+		}
+	}
+
+	n, err := snapops.Delete(ctx, target, []*rds.DBSnapshot{{DBSnapshotIdentifier: aws.String("db-1-2019-03-30-cf-ap-southeast-2")}})
+	if n != 1 || err != nil {
+		t.Errorf("snapops.Delete() = %v, %v, want %v", n, err, 1)
+	}
+	if s, err := snapops.Describe(ctx, target, "db-1-2019-03-30-cf-ap-southeast-2"); s != nil || err != nil {
+		t.Errorf("snapops.Describe() deleted = %v, %v, want nil", s, err)
+	}
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "rds_snapshot_copier"
+
+var (
+	// CopiesStarted counts the snapshot copies requested, per rds and target region
+	CopiesStarted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "copies_started_total",
+		Help:      "Snapshot copies started, per rds and target region.",
+	}, []string{"source", "target_region"})
+
+	// CopiesSucceeded counts the snapshot copies that completed, per rds and target region
+	CopiesSucceeded = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "copies_succeeded_total",
+		Help:      "Snapshot copies completed, per rds and target region.",
+	}, []string{"source", "target_region"})
+
+	// CopiesFailed counts the snapshot copies that did not complete, per rds, target region and final state
+	CopiesFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "copies_failed_total",
+		Help:      "Snapshot copies that did not complete, per rds, target region and state (failed, timed_out or vanished).",
+	}, []string{"source", "target_region", "state"})
+
+	// CopyDuration is the time taken by completed snapshot copies, per target region
+	CopyDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "copy_duration_seconds",
+		Help:      "Time taken by completed snapshot copies, per target region.",
+		Buckets:   prometheus.ExponentialBuckets(60, 2, 10), // 1 minute to 8.5 hours
+	}, []string{"target_region"})
+
+	// CopiesInFlight is the number of copies in progress, per target region
+	CopiesInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "copies_in_flight",
+		Help:      "Snapshot copies in progress, per target region.",
+	}, []string{"target_region"})
+
+	// CopiesInFlightLimit is MaxCopyInFlight, per target region
+	CopiesInFlightLimit = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "copies_in_flight_limit",
+		Help:      "Maximum snapshot copies in progress, per target region.",
+	}, []string{"target_region"})
+
+	// SnapshotsDeleted counts the snapshots deleted by retention, per rds and target region
+	SnapshotsDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "snapshots_deleted_total",
+		Help:      "Snapshots deleted from the target region by retention, per rds and target region.",
+	}, []string{"source", "target_region"})
+
+	// APIErrors counts the errors returned by AWS, per API operation and error code
+	APIErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "aws_api_errors_total",
+		Help:      "Errors returned by AWS API calls, per operation and error code.",
+	}, []string{"operation", "code"})
+
+	// CopyAge is the age of the newest copy, per rds and target region, as found by the RPO check
+	CopyAge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "newest_copy_age_seconds",
+		Help:      "Age of the source snapshot of the newest copy, per rds and target region.",
+	}, []string{"source", "target_region"})
+
+	// RPOBreached is 1 if the newest copy of an rds is older than its RPO, per rds and target region
+	RPOBreached = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rpo_breached",
+		Help:      "1 if the newest copy is older than the RPO, or there is none, per rds and target region.",
+	}, []string{"source", "target_region"})
+
+	// LastSuccess is when a cycle last completed without error
+	LastSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix time a cycle last completed without error.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(CopiesStarted, CopiesSucceeded, CopiesFailed, CopyDuration, CopiesInFlight, CopiesInFlightLimit,
+		SnapshotsDeleted, APIErrors, CopyAge, RPOBreached, LastSuccess)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// AWSErrors is an AWS SDK request handler, counting the errors returned by each API operation in APIErrors.
+// Add it to a client's Complete handlers.
+var AWSErrors = request.NamedHandler{
+	Name: "metrics.AWSErrors",
+	Fn: func(r *request.Request) {
+		if r.Error == nil || r.Operation == nil {
+			return
+		}
+		code := "unknown"
+		if aerr, ok := r.Error.(awserr.Error); ok {
+			code = aerr.Code()
+		}
+		APIErrors.WithLabelValues(r.Operation.Name, code).Inc()
+	},
+}
@@ -0,0 +1,225 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+// Policy is the per rds copy policy, parsed from the value of its copy tag, e.g. "regions=us-west-2+eu-west-1 keep=7 kms=alias/dr schedule=daily".
+// A bare list of regions ("us-west-2+eu-west-1") is also accepted, as is a legacy value of "true", "yes", "1"
+// or "", which means copy with the defaults, and "false", "no" or "0" disables copying.
+// Retention may also be set with "maxage=30" (days) and "daily=7 weekly=4 monthly=12", and the recovery point
+// objective with "rpo=26h".
+type Policy struct {
+	Regions  []string      // Target regions; empty means the configured default
+	Keep     int           // Snapshots to keep per target region; 0 means the configured default
+	MaxAge   int           // Days to keep snapshots for; 0 means the configured default
+	Daily    int           // GFS daily snapshots to keep; 0 means the configured default
+	Weekly   int           // GFS weekly snapshots to keep; 0 means the configured default
+	Monthly  int           // GFS monthly snapshots to keep; 0 means the configured default
+	KMS      string        // KMS key or alias to encrypt with in every target region
+	Schedule time.Duration // Minimum time between copies; 0 means the configured default
+	RPO      time.Duration // Maximum age of the newest copy; 0 means the configured default
+	Disabled bool          // The tag explicitly opts out of copying
+}
+
+// schedules are the named Schedule values, any Go duration is also accepted
+var schedules = map[string]time.Duration{
+	"hourly": time.Hour,
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+// legacy are the tag values, from before copy policies, which mean copy using the defaults
+var legacy = map[string]bool{"true": true, "yes": true, "1": true}
+
+// disabled are the tag values which explicitly opt out of copying
+var disabled = map[string]bool{"false": true, "no": true, "0": true}
+
+// Parse parses a copy tag value into a Policy. Its regions must not include sourceRegion.
+func Parse(tag, sourceRegion string) (*Policy, error) {
+	p := &Policy{}
+	fields := strings.Fields(tag)
+
+	if len(fields) == 1 && legacy[strings.ToLower(fields[0])] {
+		return p, nil
+	}
+	if len(fields) == 1 && disabled[strings.ToLower(fields[0])] {
+		return &Policy{Disabled: true}, nil
+	}
+
+	for _, f := range fields {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) == 1 {
+			kv = []string{"regions", f}
+		}
+		if err := p.set(kv[0], kv[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.notSource(sourceRegion); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// notSource checks the policy's regions do not include the source region, which can not be copied to
+func (p *Policy) notSource(sourceRegion string) error {
+	for _, r := range p.Regions {
+		if r == sourceRegion {
+			return fmt.Errorf("regions must not include the source region %s", sourceRegion)
+		}
+	}
+	return nil
+}
+
+// set sets a single policy key, e.g. "keep", to a value
+func (p *Policy) set(k, v string) error {
+	switch k {
+	case "regions":
+		regions, unknown := Regions(v)
+		if len(unknown) > 0 || len(regions) == 0 {
+			return fmt.Errorf("unknown regions %q, want AWS regions separated by +, or true", v)
+		}
+		p.Regions = append(p.Regions, regions...)
+	case "keep", "daily", "weekly", "monthly", "maxage":
+		n, err := strconv.Atoi(strings.TrimSuffix(v, "d"))
+		if err != nil || n < 1 {
+			return fmt.Errorf("%s must be a number greater than 0, got %q", k, v)
+		}
+		switch k {
+		case "keep":
+			p.Keep = n
+		case "daily":
+			p.Daily = n
+		case "weekly":
+			p.Weekly = n
+		case "monthly":
+			p.Monthly = n
+		case "maxage":
+			p.MaxAge = n
+		}
+	case "kms":
+		if v == "" {
+			return fmt.Errorf("kms must not be empty")
+		}
+		p.KMS = v
+	case "schedule", "rpo":
+		d, ok := schedules[v]
+		if !ok {
+			var err error
+			d, err = time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return fmt.Errorf("%s must be hourly, daily, weekly or a duration, got %q", k, v)
+			}
+		}
+		if k == "rpo" {
+			p.RPO = d
+		} else {
+			p.Schedule = d
+		}
+	default:
+		return fmt.Errorf("unknown policy key %q", k)
+	}
+	return nil
+}
+
+// FromMap builds a Policy from its keys and values, as in a copy tag, e.g. {"regions": "us-west-2+eu-west-1", "keep": "7"}
+func FromMap(settings map[string]string) (*Policy, error) {
+	keys := make([]string, 0, len(settings))
+	for k := range settings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	p := &Policy{}
+	for _, k := range keys {
+		if err := p.set(k, settings[k]); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Apply returns a copy of cfg, overridden by the policy
+func (p *Policy) Apply(cfg *wiring.Config) *wiring.Config {
+	c := *cfg
+	if len(p.Regions) > 0 {
+		c.TargetRegion = strings.Join(p.Regions, ",")
+	}
+	if p.Keep > 0 {
+		c.MaxSnap = p.Keep
+	}
+	if p.MaxAge > 0 {
+		c.MaxAgeDays = p.MaxAge
+	}
+	if p.Daily > 0 {
+		c.KeepDaily = p.Daily
+	}
+	if p.Weekly > 0 {
+		c.KeepWeekly = p.Weekly
+	}
+	if p.Monthly > 0 {
+		c.KeepMonthly = p.Monthly
+	}
+	if p.KMS != "" {
+		// An alias resolves in each target region, so the per region keys no longer apply
+		c.TargetKMS = p.KMS
+		c.TargetKMSRegion = nil
+	}
+	if p.Schedule > 0 {
+		c.Schedule = p.Schedule
+	}
+	if p.RPO > 0 {
+		c.RPO = p.RPO
+	}
+	return &c
+}
+
+// Overrides returns a copy of cfg with the config file overrides matching an rds instance or Aurora cluster
+// identifier applied, in order, so later overrides win. enabled is that of the last matching override which sets
+// it, or nil if none does.
+func Overrides(cfg *wiring.Config, id string) (c *wiring.Config, enabled *bool, err error) {
+	c = cfg
+	for _, o := range cfg.Overrides {
+		ok, err := path.Match(o.Match, id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("override %q: %v", o.Match, err)
+		}
+		if !ok {
+			continue
+		}
+		p, err := FromMap(o.Policy)
+		if err == nil {
+			err = p.notSource(cfg.SourceRegion)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("override %q: %v", o.Match, err)
+		}
+		c = p.Apply(c)
+		if o.Enabled != nil {
+			enabled = o.Enabled
+		}
+	}
+	return c, enabled, nil
+}
+
+// Regions splits a list of regions, e.g. "us-west-2+eu-west-1", into the AWS regions it names and any unknown names
+func Regions(list string) ([]string, []string) {
+	var regions, unknown []string
+	for _, r := range wiring.SplitList(list) {
+		if wiring.IsRegion(r) {
+			regions = append(regions, r)
+		} else {
+			unknown = append(unknown, r)
+		}
+	}
+	return regions, unknown
+}
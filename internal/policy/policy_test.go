@@ -0,0 +1,244 @@
+package policy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	type want struct {
+		err    bool
+		result *Policy
+	}
+	tests := []struct {
+		name string
+		tag  string
+		want want
+	}{
+		{
+			name: "Parse_legacy_value",
+			tag:  "true",
+			want: want{result: &Policy{}},
+		},
+		{
+			name: "Parse_legacy_yes",
+			tag:  "Yes",
+			want: want{result: &Policy{}},
+		},
+		{
+			name: "Parse_disabled_false",
+			tag:  "false",
+			want: want{result: &Policy{Disabled: true}},
+		},
+		{
+			name: "Parse_disabled_no",
+			tag:  "No",
+			want: want{result: &Policy{Disabled: true}},
+		},
+		{
+			name: "Parse_disabled_zero",
+			tag:  "0",
+			want: want{result: &Policy{Disabled: true}},
+		},
+		{
+			name: "Parse_empty",
+			tag:  "",
+			want: want{result: &Policy{}},
+		},
+		{
+			name: "Parse_typo_region",
+			tag:  "us-wset-2",
+			want: want{err: true},
+		},
+		{
+			name: "Parse_missing_equals",
+			tag:  "keep7",
+			want: want{err: true},
+		},
+		{
+			name: "Parse_source_region",
+			tag:  "regions=us-west-2+ap-southeast-2",
+			want: want{err: true},
+		},
+		{
+			name: "Parse_bare_source_region",
+			tag:  "ap-southeast-2",
+			want: want{err: true},
+		},
+		{
+			name: "Parse_bare_regions",
+			tag:  "us-west-2+eu-west-1",
+			want: want{result: &Policy{Regions: []string{"us-west-2", "eu-west-1"}}},
+		},
+		{
+			name: "Parse_bare_regions_unknown",
+			tag:  "us-west-2+mars-north-1",
+			want: want{err: true},
+		},
+		{
+			name: "Parse_full",
+			tag:  "regions=us-west-2+eu-west-1 keep=7 kms=alias/dr schedule=daily",
+			want: want{result: &Policy{
+				Regions:  []string{"us-west-2", "eu-west-1"},
+				Keep:     7,
+				KMS:      "alias/dr",
+				Schedule: 24 * time.Hour,
+			}},
+		},
+		{
+			name: "Parse_schedule_duration",
+			tag:  "schedule=12h",
+			want: want{result: &Policy{Schedule: 12 * time.Hour}},
+		},
+		{
+			name: "Parse_retention",
+			tag:  "maxage=30d daily=7 weekly=4 monthly=12",
+			want: want{result: &Policy{MaxAge: 30, Daily: 7, Weekly: 4, Monthly: 12}},
+		},
+		{
+			name: "Parse_bad_monthly",
+			tag:  "monthly=lots",
+			want: want{err: true},
+		},
+		{
+			name: "Parse_rpo",
+			tag:  "rpo=26h",
+			want: want{result: &Policy{RPO: 26 * time.Hour}},
+		},
+		{
+			name: "Parse_bad_keep",
+			tag:  "keep=0",
+			want: want{err: true},
+		},
+		{
+			name: "Parse_bad_schedule",
+			tag:  "schedule=fortnightly",
+			want: want{err: true},
+		},
+		{
+			name: "Parse_unknown_key",
+			tag:  "regions=us-west-2 colour=blue",
+			want: want{err: true},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.tag, "ap-southeast-2")
+
+			if (err != nil) != tt.want.err {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.want.err)
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want.result) {
+				t.Errorf("%v = %+v, want %+v", tt.name, got, tt.want.result)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+	cfg := wiring.Config{
+		MaxSnap:         2,
+		TargetKMS:       "default-key",
+		TargetKMSRegion: map[string]string{"us-west-2": "west-key"},
+		TargetRegion:    "us-east-1",
+	}
+
+	p := Policy{Regions: []string{"us-west-2", "eu-west-1"}, Keep: 7, MaxAge: 30, Weekly: 4, KMS: "alias/dr"}
+	got := p.Apply(&cfg)
+
+	want := wiring.Config{
+		KeepWeekly:   4,
+		MaxAgeDays:   30,
+		MaxSnap:      7,
+		TargetKMS:    "alias/dr",
+		TargetRegion: "us-west-2,eu-west-1",
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("Apply() = %+v, want %+v", *got, want)
+	}
+
+	// The defaults must be left untouched
+	if cfg.MaxSnap != 2 || cfg.TargetRegion != "us-east-1" {
+		t.Errorf("Apply() modified the original config: %+v", cfg)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	t.Parallel()
+	yes, no := true, false
+	cfg := &wiring.Config{
+		MaxSnap:      2,
+		TargetRegion: "us-east-1",
+		Overrides: []wiring.Override{
+			{Match: "prod-*", Enabled: &yes, Policy: map[string]string{"regions": "us-west-2+eu-west-1", "keep": "7"}},
+			{Match: "prod-reporting", Enabled: &no},
+			{Match: "prod-db-?", Policy: map[string]string{"keep": "14", "schedule": "daily"}},
+		},
+	}
+
+	type want struct {
+		err     bool
+		regions string
+		keep    int
+		enabled *bool
+	}
+	tests := []struct {
+		name string
+		id   string
+		cfg  *wiring.Config
+		want want
+	}{
+		{name: "Overrides_none_match", id: "staging-db", cfg: cfg, want: want{regions: "us-east-1", keep: 2}},
+		{name: "Overrides_pattern", id: "prod-api", cfg: cfg, want: want{regions: "us-west-2,eu-west-1", keep: 7, enabled: &yes}},
+		{name: "Overrides_later_wins", id: "prod-db-1", cfg: cfg, want: want{regions: "us-west-2,eu-west-1", keep: 14, enabled: &yes}},
+		{name: "Overrides_disabled", id: "prod-reporting", cfg: cfg, want: want{regions: "us-west-2,eu-west-1", keep: 7, enabled: &no}},
+		{
+			name: "Overrides_bad_pattern",
+			id:   "prod-api",
+			cfg:  &wiring.Config{Overrides: []wiring.Override{{Match: "prod-["}}},
+			want: want{err: true},
+		},
+		{
+			name: "Overrides_bad_policy",
+			id:   "prod-api",
+			cfg:  &wiring.Config{Overrides: []wiring.Override{{Match: "prod-*", Policy: map[string]string{"keep": "lots"}}}},
+			want: want{err: true},
+		},
+		{
+			name: "Overrides_source_region",
+			id:   "prod-api",
+			cfg:  &wiring.Config{SourceRegion: "us-west-2", Overrides: []wiring.Override{{Match: "prod-*", Policy: map[string]string{"regions": "us-west-2"}}}},
+			want: want{err: true},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, enabled, err := Overrides(tt.cfg, tt.id)
+			if (err != nil) != tt.want.err {
+				t.Fatalf("Overrides() error = %v, wantErr %v", err, tt.want.err)
+			}
+			if err != nil {
+				return
+			}
+			if got.TargetRegion != tt.want.regions || got.MaxSnap != tt.want.keep {
+				t.Errorf("%v = %v keep %v, want %v keep %v", tt.name, got.TargetRegion, got.MaxSnap, tt.want.regions, tt.want.keep)
+			}
+			if !reflect.DeepEqual(enabled, tt.want.enabled) {
+				t.Errorf("%v enabled = %v, want %v", tt.name, enabled, tt.want.enabled)
+			}
+		})
+	}
+}
@@ -1,20 +1,37 @@
 package rdsops
 
 import (
-	"time"
+	"context"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/rds"
 	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	"github.com/bluebenno/rds-snapshot-copier/internal/policy"
 	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
 	"go.uber.org/zap"
 )
 
-// AntiRateLimit will be slept in key places, to preclude AWS API rate-limiting.
-const AntiRateLimit = (10 * time.Millisecond)
+// InScope is an rds instance flagged for copying, with its config as overridden by the copy policy in its tag
+type InScope struct {
+	Instance *rds.DBInstance
+	Config   *wiring.Config
+}
+
+// InScopeCluster is an Aurora cluster flagged for copying, with its config as overridden by the copy policy in its tag
+type InScopeCluster struct {
+	Cluster *rds.DBCluster
+	Config  *wiring.Config
+}
+
+// Skipped is an rds instance or Aurora cluster that was not in scope, and why
+type Skipped struct {
+	ID     string
+	Reason string
+}
 
 // List returns all rds instances in the region. Max of 50000
-func List(rdssession rdsiface.RDSAPI) ([]*rds.DBInstance, error) {
+func List(ctx context.Context, rdssession rdsiface.RDSAPI) ([]*rds.DBInstance, error) {
 	var results []*rds.DBInstance
 
 	params := &rds.DescribeDBInstancesInput{
@@ -22,23 +39,39 @@ func List(rdssession rdsiface.RDSAPI) ([]*rds.DBInstance, error) {
 	}
 
 	pageNum := 0
-	err := rdssession.DescribeDBInstancesPages(params,
+	err := rdssession.DescribeDBInstancesPagesWithContext(ctx, params,
 		func(r *rds.DescribeDBInstancesOutput, lastPage bool) bool {
 			pageNum++
 			results = append(results, r.DBInstances...)
-			time.Sleep(AntiRateLimit)
 			return pageNum <= 1000
 		})
 
 	return results, err
 }
 
+// Get returns an rds instance, or nil if it does not exist
+func Get(ctx context.Context, rdssession rdsiface.RDSAPI, id string) (*rds.DBInstance, error) {
+	res, err := rdssession.DescribeDBInstancesWithContext(ctx, &rds.DescribeDBInstancesInput{
+		DBInstanceIdentifier: aws.String(id),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == rds.ErrCodeDBInstanceNotFoundFault {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(res.DBInstances) == 0 {
+		return nil, nil
+	}
+	return res.DBInstances[0], nil
+}
+
 // GetTag returns an AWS rds Tag value, given the Key. Otherwise returns empty string
-func GetTag(rdssession rdsiface.RDSAPI, arn, searchKey string) (string, error) {
+func GetTag(ctx context.Context, rdssession rdsiface.RDSAPI, arn, searchKey string) (string, error) {
 	c := &rds.ListTagsForResourceInput{
 		ResourceName: aws.String(arn),
 	}
-	res, err := rdssession.ListTagsForResource(c)
+	res, err := rdssession.ListTagsForResourceWithContext(ctx, c)
 	if err != nil {
 		return "", err
 	}
@@ -52,31 +85,152 @@ func GetTag(rdssession rdsiface.RDSAPI, arn, searchKey string) (string, error) {
 }
 
 // Filter takes a list of rds and indentifies the ones that need their snapshots copied
-// It does this by checking for the user supplied tag
-func Filter(logger *zap.Logger, cfg *wiring.Config, rdssession rdsiface.RDSAPI, input []*rds.DBInstance) ([]*rds.DBInstance, error) {
-	var filtered []*rds.DBInstance
+// It does this by checking for the user supplied tag, whose value is the copy policy, or a config file override
+// enabling it. See policy.Overrides.
+func Filter(ctx context.Context, logger *zap.Logger, cfg *wiring.Config, rdssession rdsiface.RDSAPI, input []*rds.DBInstance) ([]InScope, []Skipped, error) {
+	var filtered []InScope
+	var skipped []Skipped
 	for _, i := range input {
 		if *i.DBInstanceStatus != "available" {
 			logger.Info("Skipping rds with status != available", zap.String("instance", *i.DBInstanceIdentifier))
+			skipped = append(skipped, Skipped{ID: *i.DBInstanceIdentifier, Reason: "status is not available"})
+			continue
+		}
+
+		ocfg, enabled, err := policy.Overrides(cfg, *i.DBInstanceIdentifier)
+		if err != nil {
+			logger.Warn("Skipping rds with an invalid override in the config file", zap.String("instance", *i.DBInstanceIdentifier), zap.Error(err))
+			skipped = append(skipped, Skipped{ID: *i.DBInstanceIdentifier, Reason: "invalid override: " + err.Error()})
+			continue
+		}
+		if enabled != nil && !*enabled {
+			logger.Info("Skipping rds disabled in the config file", zap.String("instance", *i.DBInstanceIdentifier))
+			skipped = append(skipped, Skipped{ID: *i.DBInstanceIdentifier, Reason: "disabled in the config file"})
 			continue
 		}
 
-		t, err := GetTag(rdssession, *i.DBInstanceArn, cfg.Tag)
+		t, err := GetTag(ctx, rdssession, *i.DBInstanceArn, cfg.Tag)
 		if err != nil {
 			logger.Warn("Error encountered when checking AWS tags", zap.Any("instance", *i.DBInstanceIdentifier), zap.Error(err))
+			skipped = append(skipped, Skipped{ID: *i.DBInstanceIdentifier, Reason: "failed to read tags: " + err.Error()})
 			continue
 		}
 
-		if t == "" {
+		// An override may enable it without a tag, the tag's policy is applied over the overrides
+		if t == "" && enabled == nil {
+			continue
+		}
+
+		p, err := policy.Parse(t, cfg.SourceRegion)
+		if err != nil {
+			logger.Warn("Skipping rds with an invalid copy policy in its tag", zap.String("instance", *i.DBInstanceIdentifier), zap.String("tag", t), zap.Error(err))
+			skipped = append(skipped, Skipped{ID: *i.DBInstanceIdentifier, Reason: "invalid copy policy: " + err.Error()})
+			continue
+		}
+		if p.Disabled {
+			logger.Info("Skipping rds disabled by its tag", zap.String("instance", *i.DBInstanceIdentifier))
+			skipped = append(skipped, Skipped{ID: *i.DBInstanceIdentifier, Reason: "disabled by tag"})
+			continue
+		}
+		icfg := p.Apply(ocfg)
+
+		logger.Info("found in scope rds", zap.String("instance", *i.DBInstanceIdentifier), zap.Strings("target_regions", icfg.TargetRegions()))
+		filtered = append(filtered, InScope{Instance: i, Config: icfg})
+	}
+
+	return filtered, skipped, nil
+}
+
+// ListClusters returns all Aurora db clusters in the region. Max of 50000
+func ListClusters(ctx context.Context, rdssession rdsiface.RDSAPI) ([]*rds.DBCluster, error) {
+	var results []*rds.DBCluster
+
+	params := &rds.DescribeDBClustersInput{
+		MaxRecords: aws.Int64(50),
+	}
+
+	pageNum := 0
+	err := rdssession.DescribeDBClustersPagesWithContext(ctx, params,
+		func(r *rds.DescribeDBClustersOutput, lastPage bool) bool {
+			pageNum++
+			results = append(results, r.DBClusters...)
+			return pageNum <= 1000
+		})
+
+	return results, err
+}
+
+// GetCluster returns an Aurora cluster, or nil if it does not exist
+func GetCluster(ctx context.Context, rdssession rdsiface.RDSAPI, id string) (*rds.DBCluster, error) {
+	res, err := rdssession.DescribeDBClustersWithContext(ctx, &rds.DescribeDBClustersInput{
+		DBClusterIdentifier: aws.String(id),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == rds.ErrCodeDBClusterNotFoundFault {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(res.DBClusters) == 0 {
+		return nil, nil
+	}
+	return res.DBClusters[0], nil
+}
+
+// FilterClusters takes a list of Aurora clusters and indentifies the ones that need their snapshots copied
+// It does this by checking for the user supplied tag, whose value is the copy policy, or a config file override
+// enabling it. See policy.Overrides.
+func FilterClusters(ctx context.Context, logger *zap.Logger, cfg *wiring.Config, rdssession rdsiface.RDSAPI, input []*rds.DBCluster) ([]InScopeCluster, []Skipped, error) {
+	var filtered []InScopeCluster
+	var skipped []Skipped
+	for _, c := range input {
+		if *c.Status != "available" {
+			logger.Info("Skipping cluster with status != available", zap.String("cluster", *c.DBClusterIdentifier))
+			skipped = append(skipped, Skipped{ID: *c.DBClusterIdentifier, Reason: "status is not available"})
+			continue
+		}
+
+		ocfg, enabled, err := policy.Overrides(cfg, *c.DBClusterIdentifier)
+		if err != nil {
+			logger.Warn("Skipping cluster with an invalid override in the config file", zap.String("cluster", *c.DBClusterIdentifier), zap.Error(err))
+			skipped = append(skipped, Skipped{ID: *c.DBClusterIdentifier, Reason: "invalid override: " + err.Error()})
+			continue
+		}
+		if enabled != nil && !*enabled {
+			logger.Info("Skipping cluster disabled in the config file", zap.String("cluster", *c.DBClusterIdentifier))
+			skipped = append(skipped, Skipped{ID: *c.DBClusterIdentifier, Reason: "disabled in the config file"})
+			continue
+		}
+
+		t, err := GetTag(ctx, rdssession, *c.DBClusterArn, cfg.Tag)
+		if err != nil {
+			logger.Warn("Error encountered when checking AWS tags", zap.Any("cluster", *c.DBClusterIdentifier), zap.Error(err))
+			skipped = append(skipped, Skipped{ID: *c.DBClusterIdentifier, Reason: "failed to read tags: " + err.Error()})
+			continue
+		}
+
+		// An override may enable it without a tag, the tag's policy is applied over the overrides
+		if t == "" && enabled == nil {
+			continue
+		}
+
+		p, err := policy.Parse(t, cfg.SourceRegion)
+		if err != nil {
+			logger.Warn("Skipping cluster with an invalid copy policy in its tag", zap.String("cluster", *c.DBClusterIdentifier), zap.String("tag", t), zap.Error(err))
+			skipped = append(skipped, Skipped{ID: *c.DBClusterIdentifier, Reason: "invalid copy policy: " + err.Error()})
+			continue
+		}
+		if p.Disabled {
+			logger.Info("Skipping cluster disabled by its tag", zap.String("cluster", *c.DBClusterIdentifier))
+			skipped = append(skipped, Skipped{ID: *c.DBClusterIdentifier, Reason: "disabled by tag"})
 			continue
 		}
+		ccfg := p.Apply(ocfg)
 
-		logger.Info("found in scope rds", zap.String("instance", *i.DBInstanceIdentifier))
-		filtered = append(filtered, i)
+		logger.Info("found in scope cluster", zap.String("cluster", *c.DBClusterIdentifier), zap.Strings("target_regions", ccfg.TargetRegions()))
+		filtered = append(filtered, InScopeCluster{Cluster: c, Config: ccfg})
 
-		//
-		time.Sleep(AntiRateLimit)
 	}
 
-	return filtered, nil
+	return filtered, skipped, nil
 }
@@ -1,11 +1,13 @@
 package rdsops
 
 import (
+	"context"
 	"log"
 	"reflect"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/rds"
 	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
 	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
@@ -73,7 +75,7 @@ func TestList(t *testing.T) {
 				describeDBInstancesOutput: tt.awsmockresult,
 			}
 
-			got, err := List(mockSvc)
+			got, err := List(context.Background(), mockSvc)
 			if (err != nil) != tt.want.err {
 				t.Errorf("List() error = %v, wantErr %v", err, tt.want.err)
 				return
@@ -87,6 +89,23 @@ func TestList(t *testing.T) {
 	}
 }
 
+func TestGet(t *testing.T) {
+	t.Parallel()
+	db1 := rds.DBInstance{DBInstanceIdentifier: aws.String("db-1")}
+
+	mockSvc := &mockRDSClient{describeDBInstancesOutput: &rds.DescribeDBInstancesOutput{DBInstances: []*rds.DBInstance{&db1}}}
+	got, err := Get(context.Background(), mockSvc, "db-1")
+	if err != nil || got != &db1 {
+		t.Errorf("Get() = %v, %v, want %v", got, err, &db1)
+	}
+
+	mockSvc = &mockRDSClient{describeDBInstancesOutput: &rds.DescribeDBInstancesOutput{}}
+	got, err = Get(context.Background(), mockSvc, "db-2")
+	if err != nil || got != nil {
+		t.Errorf("Get() of a missing instance = %v, %v, want nil", got, err)
+	}
+}
+
 func TestGetTag(t *testing.T) {
 	t.Parallel()
 
@@ -147,7 +166,7 @@ func TestGetTag(t *testing.T) {
 				listTagsForResourceOutput: tt.awsmockresult,
 			}
 
-			got, err := GetTag(mockSvc, tt.args.arn, tt.args.search)
+			got, err := GetTag(context.Background(), mockSvc, tt.args.arn, tt.args.search)
 
 			if (err != nil) != tt.want.err {
 				t.Errorf("List() error = %v, wantErr %v", err, tt.want.err)
@@ -176,23 +195,33 @@ func TestFilter(t *testing.T) {
 	}
 	instances01 := []*rds.DBInstance{&i01}
 	instances02 := []*rds.DBInstance{&i02}
+	inscope01 := []InScope{{Instance: &i01, Config: &wiring.Config{Tag: "copythisone", TargetRegion: "ap-southeast-1"}}}
+	inscope01tagged := []InScope{{Instance: &i01, Config: &wiring.Config{Tag: "copythisone", TargetRegion: "us-west-2,eu-west-1", MaxSnap: 3}}}
 
 	logger, err := zap.NewProduction()
 	if err != nil {
 		log.Fatalf("Unable to create logger: %s", err.Error())
 	}
 
-	var cfg wiring.Config
+	cfg := wiring.Config{TargetRegion: "ap-southeast-1"}
+
+	yes, no := true, false
+	enable := []wiring.Override{{Match: "instance-*", Enabled: &yes, Policy: map[string]string{"regions": "us-west-2", "keep": "5"}}}
+	disable := []wiring.Override{{Match: "instance-i01", Enabled: &no}}
+	inscope01enabled := []InScope{{Instance: &i01, Config: &wiring.Config{Tag: "copythisone", TargetRegion: "us-west-2", MaxSnap: 5, Overrides: enable}}}
+	inscope01overridden := []InScope{{Instance: &i01, Config: &wiring.Config{Tag: "copythisone", TargetRegion: "us-west-2", MaxSnap: 3, Overrides: enable}}}
 
 	type args struct {
-		logger *zap.Logger
-		input  []*rds.DBInstance
-		cfg    *wiring.Config
-		tag    string
+		logger    *zap.Logger
+		input     []*rds.DBInstance
+		cfg       *wiring.Config
+		tag       string
+		overrides []wiring.Override
 	}
 	type want struct {
-		err    bool
-		result []*rds.DBInstance
+		err     bool
+		result  []InScope
+		skipped int
 	}
 	tests := []struct {
 		args          args
@@ -208,12 +237,65 @@ func TestFilter(t *testing.T) {
 				tag:    "copythisone",
 			},
 			want: want{
-				result: instances01,
+				result: inscope01,
 				err:    false,
 			},
 			awsmockresult: &rds.ListTagsForResourceOutput{
 				TagList: []*rds.Tag{
-					{Key: aws.String("copythisone"), Value: aws.String("anyvalue")},
+					{Key: aws.String("copythisone"), Value: aws.String("true")},
+				},
+			},
+		},
+		{
+			name: "Filter_found-i01-regions",
+			args: args{
+				logger: logger,
+				input:  instances01,
+				tag:    "copythisone",
+			},
+			want: want{
+				result: inscope01tagged,
+				err:    false,
+			},
+			awsmockresult: &rds.ListTagsForResourceOutput{
+				TagList: []*rds.Tag{
+					{Key: aws.String("copythisone"), Value: aws.String("regions=us-west-2+eu-west-1 keep=3")},
+				},
+			},
+		},
+		{
+			name: "Filter_found-i01-invalid-policy",
+			args: args{
+				logger: logger,
+				input:  instances01,
+				tag:    "copythisone",
+			},
+			want: want{
+				result:  nil,
+				err:     false,
+				skipped: 1,
+			},
+			awsmockresult: &rds.ListTagsForResourceOutput{
+				TagList: []*rds.Tag{
+					{Key: aws.String("copythisone"), Value: aws.String("keep=lots")},
+				},
+			},
+		},
+		{
+			name: "Filter_found-i01-disabled-by-tag",
+			args: args{
+				logger: logger,
+				input:  instances01,
+				tag:    "copythisone",
+			},
+			want: want{
+				result:  nil,
+				err:     false,
+				skipped: 1,
+			},
+			awsmockresult: &rds.ListTagsForResourceOutput{
+				TagList: []*rds.Tag{
+					{Key: aws.String("copythisone"), Value: aws.String("false")},
 				},
 			},
 		},
@@ -233,6 +315,71 @@ func TestFilter(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Filter_override-enables-untagged",
+			args: args{
+				logger:    logger,
+				input:     instances01,
+				tag:       "copythisone",
+				overrides: enable,
+			},
+			want: want{
+				result: inscope01enabled,
+			},
+			awsmockresult: &rds.ListTagsForResourceOutput{},
+		},
+		{
+			name: "Filter_override-then-tag",
+			args: args{
+				logger:    logger,
+				input:     instances01,
+				tag:       "copythisone",
+				overrides: enable,
+			},
+			want: want{
+				result: inscope01overridden,
+			},
+			awsmockresult: &rds.ListTagsForResourceOutput{
+				TagList: []*rds.Tag{
+					{Key: aws.String("copythisone"), Value: aws.String("keep=3")},
+				},
+			},
+		},
+		{
+			name: "Filter_override-disables-tagged",
+			args: args{
+				logger:    logger,
+				input:     instances01,
+				tag:       "copythisone",
+				overrides: disable,
+			},
+			want: want{
+				result:  nil,
+				skipped: 1,
+			},
+			awsmockresult: &rds.ListTagsForResourceOutput{
+				TagList: []*rds.Tag{
+					{Key: aws.String("copythisone"), Value: aws.String("true")},
+				},
+			},
+		},
+		{
+			name: "Filter_found-i01-typo-region",
+			args: args{
+				logger: logger,
+				input:  instances01,
+				tag:    "copythisone",
+			},
+			want: want{
+				result:  nil,
+				skipped: 1,
+			},
+			awsmockresult: &rds.ListTagsForResourceOutput{
+				TagList: []*rds.Tag{
+					{Key: aws.String("copythisone"), Value: aws.String("us-wset-2")},
+				},
+			},
+		},
 		{
 			name: "Filter_found-i02-not-available",
 			args: args{
@@ -241,12 +388,13 @@ func TestFilter(t *testing.T) {
 				tag:    "copythisone",
 			},
 			want: want{
-				result: nil,
-				err:    false,
+				result:  nil,
+				err:     false,
+				skipped: 1,
 			},
 			awsmockresult: &rds.ListTagsForResourceOutput{
 				TagList: []*rds.Tag{
-					{Key: aws.String("copythisone"), Value: aws.String("anyvalue")},
+					{Key: aws.String("copythisone"), Value: aws.String("true")},
 				},
 			},
 		},
@@ -259,7 +407,8 @@ func TestFilter(t *testing.T) {
 				listTagsForResourceOutput: tt.awsmockresult,
 			}
 			cfg.Tag = tt.args.tag
-			got, err := Filter(tt.args.logger, &cfg, mockSvc, tt.args.input)
+			cfg.Overrides = tt.args.overrides
+			got, skipped, err := Filter(context.Background(), tt.args.logger, &cfg, mockSvc, tt.args.input)
 
 			if (err != nil) != tt.want.err {
 				t.Errorf("List() error = %v, wantErr %v", err, tt.want.err)
@@ -269,30 +418,135 @@ func TestFilter(t *testing.T) {
 			if !reflect.DeepEqual(got, tt.want.result) {
 				t.Errorf("%v = %v, want %v", tt.name, got, tt.want.result)
 			}
+			if len(skipped) != tt.want.skipped {
+				t.Errorf("%v skipped = %v, want %d", tt.name, skipped, tt.want.skipped)
+			}
 
 		})
 	}
 }
 
+func TestFilterClusters(t *testing.T) {
+	t.Parallel()
+	c01 := rds.DBCluster{
+		DBClusterIdentifier: aws.String("cluster-c01"),
+		Status:              aws.String("available"),
+		DBClusterArn:        aws.String("dummyarn1"),
+	}
+	c02 := rds.DBCluster{
+		DBClusterIdentifier: aws.String("cluster-c02"),
+		Status:              aws.String("backing-up"),
+		DBClusterArn:        aws.String("dummyarn2"),
+	}
+
+	logger, err := zap.NewProduction()
+	if err != nil {
+		log.Fatalf("Unable to create logger: %s", err.Error())
+	}
+
+	type want struct {
+		err    bool
+		result []InScopeCluster
+	}
+	tests := []struct {
+		input         []*rds.DBCluster
+		awsmockresult *rds.ListTagsForResourceOutput
+		name          string
+		want          want
+	}{
+		{
+			name:  "FilterClusters_found-c01-available",
+			input: []*rds.DBCluster{&c01},
+			want: want{
+				result: []InScopeCluster{{Cluster: &c01, Config: &wiring.Config{Tag: "copythisone", TargetRegion: "us-west-2"}}},
+			},
+			awsmockresult: &rds.ListTagsForResourceOutput{
+				TagList: []*rds.Tag{
+					{Key: aws.String("copythisone"), Value: aws.String("true")},
+				},
+			},
+		},
+		{
+			name:  "FilterClusters_c01-disabled-by-tag",
+			input: []*rds.DBCluster{&c01},
+			want: want{
+				result: nil,
+			},
+			awsmockresult: &rds.ListTagsForResourceOutput{
+				TagList: []*rds.Tag{
+					{Key: aws.String("copythisone"), Value: aws.String("no")},
+				},
+			},
+		},
+		{
+			name:  "FilterClusters_c01-nottagged",
+			input: []*rds.DBCluster{&c01},
+			want: want{
+				result: nil,
+			},
+			awsmockresult: &rds.ListTagsForResourceOutput{},
+		},
+		{
+			name:  "FilterClusters_c02-not-available",
+			input: []*rds.DBCluster{&c02},
+			want: want{
+				result: nil,
+			},
+			awsmockresult: &rds.ListTagsForResourceOutput{
+				TagList: []*rds.Tag{
+					{Key: aws.String("copythisone"), Value: aws.String("true")},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockRDSClient{
+				listTagsForResourceOutput: tt.awsmockresult,
+			}
+			cfg := wiring.Config{Tag: "copythisone", TargetRegion: "us-west-2"}
+			got, _, err := FilterClusters(context.Background(), logger, &cfg, mockSvc, tt.input)
+
+			if (err != nil) != tt.want.err {
+				t.Errorf("FilterClusters() error = %v, wantErr %v", err, tt.want.err)
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want.result) {
+				t.Errorf("%v = %v, want %v", tt.name, got, tt.want.result)
+			}
+		})
+	}
+}
+
 // Defines a mock struct to be used for unit tests
 type mockRDSClient struct {
 	rdsiface.RDSAPI
 	describeDBInstancesOutput *rds.DescribeDBInstancesOutput
 	listTagsForResourceOutput *rds.ListTagsForResourceOutput
+	describeDBClustersOutput  *rds.DescribeDBClustersOutput
 }
 
-// Mock DescribeDBInstances
-func (m *mockRDSClient) DescribeDBInstances(i *rds.DescribeDBInstancesInput) (*rds.DescribeDBInstancesOutput, error) {
+// Mock DescribeDBInstancesWithContext
+func (m *mockRDSClient) DescribeDBInstancesWithContext(ctx aws.Context, i *rds.DescribeDBInstancesInput, opts ...request.Option) (*rds.DescribeDBInstancesOutput, error) {
 	return m.describeDBInstancesOutput, nil
 }
 
-// Mock ListTagsForResource
-func (m *mockRDSClient) ListTagsForResource(i *rds.ListTagsForResourceInput) (*rds.ListTagsForResourceOutput, error) {
+// Mock ListTagsForResourceWithContext
+func (m *mockRDSClient) ListTagsForResourceWithContext(ctx aws.Context, i *rds.ListTagsForResourceInput, opts ...request.Option) (*rds.ListTagsForResourceOutput, error) {
 	return m.listTagsForResourceOutput, nil
 }
 
-// Mock DescribeDBInstancesPages
-func (m *mockRDSClient) DescribeDBInstancesPages(i *rds.DescribeDBInstancesInput, fn func(*rds.DescribeDBInstancesOutput, bool) bool) error {
+// Mock DescribeDBInstancesPagesWithContext
+func (m *mockRDSClient) DescribeDBInstancesPagesWithContext(ctx aws.Context, i *rds.DescribeDBInstancesInput, fn func(*rds.DescribeDBInstancesOutput, bool) bool, opts ...request.Option) error {
 	fn(m.describeDBInstancesOutput, true)
 	return nil
 }
+
+// Mock DescribeDBClustersPagesWithContext
+func (m *mockRDSClient) DescribeDBClustersPagesWithContext(ctx aws.Context, i *rds.DescribeDBClustersInput, fn func(*rds.DescribeDBClustersOutput, bool) bool, opts ...request.Option) error {
+	fn(m.describeDBClustersOutput, true)
+	return nil
+}
@@ -0,0 +1,314 @@
+package snapops
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+// ListCluster will list all the snapshots for a given Aurora cluster
+func ListCluster(ctx context.Context, rdssession rdsiface.RDSAPI, cluster string) ([]*rds.DBClusterSnapshot, error) {
+
+	input := &rds.DescribeDBClusterSnapshotsInput{
+		DBClusterIdentifier: aws.String(cluster),
+		IncludePublic:       aws.Bool(false),
+		IncludeShared:       aws.Bool(false),
+		MaxRecords:          aws.Int64(20),
+	}
+
+	all, err := listClusterSnapshots(ctx, rdssession, input)
+	if err != nil {
+		return nil, err
+	}
+	var res []*rds.DBClusterSnapshot
+	for _, s := range all {
+		if !IsShare(aws.StringValue(s.DBClusterSnapshotIdentifier)) {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+// ListCopyingCluster lists the Aurora cluster snapshots being copied into the region from another region, by anything
+func ListCopyingCluster(ctx context.Context, rdssessiontarget rdsiface.RDSAPI) ([]*rds.DBClusterSnapshot, error) {
+	all, err := ListCopiedCluster(ctx, rdssessiontarget)
+	if err != nil {
+		return nil, err
+	}
+	var res []*rds.DBClusterSnapshot
+	for _, s := range all {
+		if InProgress(aws.StringValue(s.Status)) {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+// ListCopiedCluster lists the Aurora cluster snapshots copied into the region from another region, by anything,
+// whatever their state
+func ListCopiedCluster(ctx context.Context, rdssessiontarget rdsiface.RDSAPI) ([]*rds.DBClusterSnapshot, error) {
+
+	input := &rds.DescribeDBClusterSnapshotsInput{
+		SnapshotType: aws.String("manual"),
+		MaxRecords:   aws.Int64(100),
+	}
+
+	all, err := listClusterSnapshots(ctx, rdssessiontarget, input)
+	if err != nil {
+		return nil, err
+	}
+	var res []*rds.DBClusterSnapshot
+	for _, s := range all {
+		if aws.StringValue(s.SourceDBClusterSnapshotArn) != "" {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+// ListSharesCluster lists the manual copies of automated Aurora cluster snapshots made by this tool to share them
+// with the account copying them to targetRegion, see ShareName, whatever their state
+func ListSharesCluster(ctx context.Context, rdssessionsource rdsiface.RDSAPI, targetRegion string) ([]*rds.DBClusterSnapshot, error) {
+
+	input := &rds.DescribeDBClusterSnapshotsInput{
+		SnapshotType: aws.String("manual"),
+		MaxRecords:   aws.Int64(100),
+	}
+
+	all, err := listClusterSnapshots(ctx, rdssessionsource, input)
+	if err != nil {
+		return nil, err
+	}
+	var res []*rds.DBClusterSnapshot
+	for _, s := range all {
+		if strings.HasSuffix(aws.StringValue(s.DBClusterSnapshotIdentifier), "-share-"+targetRegion) {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+// listClusterSnapshots returns every page of DescribeDBClusterSnapshots.
+// The SDK has no DescribeDBClusterSnapshotsPages, so follow the Marker by hand
+func listClusterSnapshots(ctx context.Context, rdssession rdsiface.RDSAPI, input *rds.DescribeDBClusterSnapshotsInput) ([]*rds.DBClusterSnapshot, error) {
+	var res []*rds.DBClusterSnapshot
+	for pageNum := 1; pageNum <= 1000; pageNum++ {
+		r, err := rdssession.DescribeDBClusterSnapshotsWithContext(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, r.DBClusterSnapshots...)
+
+		if r.Marker == nil || *r.Marker == "" {
+			break
+		}
+		input.Marker = r.Marker
+	}
+
+	return res, nil
+}
+
+// DescribeCluster will describe an Aurora cluster snapshot
+func DescribeCluster(ctx context.Context, rdssession rdsiface.RDSAPI, snap string) (*rds.DBClusterSnapshot, error) {
+
+	input := &rds.DescribeDBClusterSnapshotsInput{
+		DBClusterSnapshotIdentifier: aws.String(snap),
+		MaxRecords:                  aws.Int64(20), // AWS constraint; min 20
+	}
+
+	res, err := rdssession.DescribeDBClusterSnapshotsWithContext(ctx, input)
+	if err != nil {
+		err, ok := err.(awserr.Error)
+		if ok && err.Code() == rds.ErrCodeDBClusterSnapshotNotFoundFault {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if len(res.DBClusterSnapshots) == 1 {
+		return res.DBClusterSnapshots[0], nil
+	}
+
+	return nil, err
+}
+
+// PullClusterSnapShot pull a copy of an Aurora cluster snapshot from a remote region. It is not blocking.
+func PullClusterSnapShot(ctx context.Context, cfg *wiring.Config, rdssession rdsiface.RDSAPI, arn *string, targetsnapshotname string, tags []*rds.Tag) (*rds.CopyDBClusterSnapshotOutput, error) {
+	input := &rds.CopyDBClusterSnapshotInput{
+		SourceDBClusterSnapshotIdentifier: aws.String(*arn),
+		TargetDBClusterSnapshotIdentifier: aws.String(targetsnapshotname),
+		DestinationRegion:                 aws.String(cfg.TargetRegion),
+		KmsKeyId:                          aws.String(cfg.TargetKMS),
+		Tags:                              tags,
+	}
+	result, err := rdssession.CopyDBClusterSnapshotWithContext(ctx, input)
+	return result, err
+}
+
+// PullEncryptedClusterSnapShot pulls an encrypted Aurora cluster snapshot from a remote region. It is not blocking.
+func PullEncryptedClusterSnapShot(ctx context.Context, cfg *wiring.Config, rdssessionsource rdsiface.RDSAPI, rdssessiontarget rdsiface.RDSAPI, arn *string, targetsnapshotname string, tags []*rds.Tag) (*rds.CopyDBClusterSnapshotOutput, error) {
+	// Build the PreSignedUrl containing the CopyDBClusterSnapshot API
+	inputps := &rds.CopyDBClusterSnapshotInput{
+		SourceDBClusterSnapshotIdentifier: aws.String(*arn),
+		TargetDBClusterSnapshotIdentifier: aws.String(targetsnapshotname),
+		DestinationRegion:                 aws.String(cfg.TargetRegion),
+		SourceRegion:                      aws.String(cfg.SourceRegion),
+		KmsKeyId:                          aws.String(cfg.TargetKMS),
+	}
+	request, _ := rdssessionsource.CopyDBClusterSnapshotRequest(inputps) // _ is output, never populated in this case as it isn't run
+	psurl, err := request.Presign(100 * time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &rds.CopyDBClusterSnapshotInput{
+		SourceDBClusterSnapshotIdentifier: aws.String(*arn),
+		TargetDBClusterSnapshotIdentifier: aws.String(targetsnapshotname),
+		DestinationRegion:                 aws.String(cfg.TargetRegion),
+		KmsKeyId:                          aws.String(cfg.TargetKMS),
+		PreSignedUrl:                      aws.String(psurl),
+		Tags:                              tags,
+	}
+	result, err := rdssessiontarget.CopyDBClusterSnapshotWithContext(ctx, input)
+	return result, err
+}
+
+// ShareCluster shares an Aurora cluster snapshot with another AWS account, so that account may copy it.
+// Only manual snapshots may be shared.
+func ShareCluster(ctx context.Context, rdssessionsource rdsiface.RDSAPI, snap, account string) error {
+	input := &rds.ModifyDBClusterSnapshotAttributeInput{
+		AttributeName:               aws.String("restore"),
+		DBClusterSnapshotIdentifier: aws.String(snap),
+		ValuesToAdd:                 []*string{aws.String(account)},
+	}
+	_, err := rdssessionsource.ModifyDBClusterSnapshotAttributeWithContext(ctx, input)
+	return err
+}
+
+// UnshareCluster stops sharing an Aurora cluster snapshot with another AWS account
+func UnshareCluster(ctx context.Context, rdssessionsource rdsiface.RDSAPI, snap, account string) error {
+	input := &rds.ModifyDBClusterSnapshotAttributeInput{
+		AttributeName:               aws.String("restore"),
+		DBClusterSnapshotIdentifier: aws.String(snap),
+		ValuesToRemove:              []*string{aws.String(account)},
+	}
+	_, err := rdssessionsource.ModifyDBClusterSnapshotAttributeWithContext(ctx, input)
+	return err
+}
+
+// CopyLocalCluster copies an Aurora cluster snapshot within its region, e.g. an automated snapshot to a manual one.
+// It is not blocking.
+func CopyLocalCluster(ctx context.Context, rdssession rdsiface.RDSAPI, snap, targetsnapshotname string, tags []*rds.Tag) (*rds.DBClusterSnapshot, error) {
+	input := &rds.CopyDBClusterSnapshotInput{
+		SourceDBClusterSnapshotIdentifier: aws.String(snap),
+		TargetDBClusterSnapshotIdentifier: aws.String(targetsnapshotname),
+		Tags:                              tags,
+	}
+	result, err := rdssession.CopyDBClusterSnapshotWithContext(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return result.DBClusterSnapshot, nil
+}
+
+// ListExpiredCluster lists the snapshots for an Aurora cluster, that are considered expired.
+// Only snapshots copied by this tool (see TargetName) are considered. See Retain for the retention rules.
+func ListExpiredCluster(ctx context.Context, cfg *wiring.Config, rdssessiontarget rdsiface.RDSAPI, cluster *rds.DBCluster) ([]*rds.DBClusterSnapshot, error) {
+	_, expired, err := ListRetentionCluster(ctx, cfg, rdssessiontarget, cluster)
+	return expired, err
+}
+
+// CopiesCluster returns the Aurora cluster snapshots that were copied from the source region by this tool
+func CopiesCluster(cfg *wiring.Config, all []*rds.DBClusterSnapshot) []*rds.DBClusterSnapshot {
+	var copies []*rds.DBClusterSnapshot
+	for _, s := range all {
+		if IsCopy(cfg, aws.StringValue(s.DBClusterSnapshotIdentifier)) {
+			copies = append(copies, s)
+		}
+	}
+	return copies
+}
+
+// DeleteCluster will delete a list of Aurora cluster snapshots.
+func DeleteCluster(ctx context.Context, rdssessiontarget rdsiface.RDSAPI, snaps []*rds.DBClusterSnapshot) (int, error) {
+	if snaps == nil {
+		return 0, nil
+	}
+
+	var count int
+	for _, i := range snaps {
+		del := &rds.DeleteDBClusterSnapshotInput{
+			DBClusterSnapshotIdentifier: i.DBClusterSnapshotIdentifier,
+		}
+		r, e := rdssessiontarget.DeleteDBClusterSnapshotWithContext(ctx, del)
+		if e != nil {
+			return 0, e
+		}
+
+		if *r.DBClusterSnapshot.Status == "deleted" {
+			count++
+		}
+
+	}
+	return count, nil
+}
+
+// SortCluster returns a copy of a list of Aurora cluster snapshots, ordered oldest first by SnapshotCreateTime.
+// As per Sort.
+func SortCluster(all []*rds.DBClusterSnapshot) []*rds.DBClusterSnapshot {
+	sorted := make([]*rds.DBClusterSnapshot, len(all))
+	copy(sorted, all)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return older(sorted[i].SnapshotCreateTime, sorted[j].SnapshotCreateTime, aws.StringValue(sorted[i].DBClusterSnapshotIdentifier), aws.StringValue(sorted[j].DBClusterSnapshotIdentifier))
+	})
+	return sorted
+}
+
+// GetLatestCluster returns the most recent snapshot for an Aurora cluster
+func GetLatestCluster(all []*rds.DBClusterSnapshot) (*rds.DBClusterSnapshot, error) {
+	if len(all) == 0 {
+		return nil, nil
+	}
+	return SortCluster(all)[len(all)-1], nil
+}
+
+// MissingCluster returns the available source Aurora cluster snapshots taken after since that have no copy in the
+// target, oldest first. As for Missing, those the target's retention rules would delete straight away are left out.
+func MissingCluster(cfg *wiring.Config, source, target []*rds.DBClusterSnapshot, since time.Time) []*rds.DBClusterSnapshot {
+	copies := make(map[string]*time.Time)
+	for _, t := range CopiesCluster(cfg, target) {
+		copies[*t.DBClusterSnapshotIdentifier] = t.SnapshotCreateTime
+	}
+
+	candidates := make(map[string]*time.Time)
+	for _, s := range source {
+		if aws.StringValue(s.Status) != "available" || s.SnapshotCreateTime == nil || !s.SnapshotCreateTime.After(since) {
+			continue
+		}
+		if _, ok := copies[TargetName(cfg, *s.DBClusterSnapshotIdentifier)]; ok {
+			continue
+		}
+		candidates[TargetName(cfg, *s.DBClusterSnapshotIdentifier)] = s.SnapshotCreateTime
+	}
+
+	keep := wouldKeep(cfg, copies, candidates, time.Now())
+	var missing []*rds.DBClusterSnapshot
+	for _, s := range source {
+		if keep[TargetName(cfg, *s.DBClusterSnapshotIdentifier)] {
+			missing = append(missing, s)
+		}
+	}
+
+	if missing == nil {
+		return nil
+	}
+	return SortCluster(missing)
+}
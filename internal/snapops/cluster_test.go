@@ -0,0 +1,171 @@
+package snapops
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+func TestListCluster(t *testing.T) {
+	t.Parallel()
+	c1s1 := rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String("cluster-one"),
+		DBClusterSnapshotIdentifier: aws.String("cluster-one-snap01"),
+	}
+	c1s2 := rds.DBClusterSnapshot{
+		DBClusterIdentifier:         aws.String("cluster-one"),
+		DBClusterSnapshotIdentifier: aws.String("cluster-one-snap02"),
+	}
+
+	type want struct {
+		err    bool
+		result []*rds.DBClusterSnapshot
+	}
+	tests := []struct {
+		awsmockresult []*rds.DescribeDBClusterSnapshotsOutput
+		name          string
+		want          want
+	}{
+		{
+			name: "ListCluster_onepage",
+			want: want{
+				result: []*rds.DBClusterSnapshot{&c1s1},
+			},
+			awsmockresult: []*rds.DescribeDBClusterSnapshotsOutput{
+				{DBClusterSnapshots: []*rds.DBClusterSnapshot{&c1s1}},
+			},
+		},
+		{
+			name: "ListCluster_twopages",
+			want: want{
+				result: []*rds.DBClusterSnapshot{&c1s1, &c1s2},
+			},
+			awsmockresult: []*rds.DescribeDBClusterSnapshotsOutput{
+				{DBClusterSnapshots: []*rds.DBClusterSnapshot{&c1s1}, Marker: aws.String("page2")},
+				{DBClusterSnapshots: []*rds.DBClusterSnapshot{&c1s2}},
+			},
+		},
+		{
+			name: "ListCluster_nonefound",
+			want: want{
+				result: nil,
+			},
+			awsmockresult: []*rds.DescribeDBClusterSnapshotsOutput{{}},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockRDSClient{
+				describeDBClusterSnapshotsOutput: tt.awsmockresult,
+			}
+
+			got, err := ListCluster(context.Background(), mockSvc, "cluster-one")
+
+			if (err != nil) != tt.want.err {
+				t.Errorf("ListCluster() error = %v, wantErr %v", err, tt.want.err)
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want.result) {
+				t.Errorf("%v = %v, want %v", tt.name, got, tt.want.result)
+			}
+		})
+	}
+}
+
+func TestListExpiredCluster(t *testing.T) {
+	t.Parallel()
+	c1s1 := rds.DBClusterSnapshot{DBClusterSnapshotIdentifier: aws.String("cluster-one-snap01-cf-ap-southeast-2")}
+	c1s2 := rds.DBClusterSnapshot{DBClusterSnapshotIdentifier: aws.String("cluster-one-snap02-cf-ap-southeast-2")}
+	c1s3 := rds.DBClusterSnapshot{DBClusterSnapshotIdentifier: aws.String("cluster-one-snap03-cf-ap-southeast-2")}
+
+	type want struct {
+		err    bool
+		result []*rds.DBClusterSnapshot
+	}
+	tests := []struct {
+		config wiring.Config
+		name   string
+		want   want
+	}{
+		{
+			name:   "ListExpiredCluster_retain1_expire2",
+			config: wiring.Config{MaxSnap: 1, SourceRegion: "ap-southeast-2"},
+			want: want{
+				result: []*rds.DBClusterSnapshot{&c1s1, &c1s2},
+			},
+		},
+		{
+			name:   "ListExpiredCluster_retain3_expire0",
+			config: wiring.Config{MaxSnap: 3, SourceRegion: "ap-southeast-2"},
+			want: want{
+				result: nil,
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			mockSvc := &mockRDSClient{
+				describeDBClusterSnapshotsOutput: []*rds.DescribeDBClusterSnapshotsOutput{
+					{DBClusterSnapshots: []*rds.DBClusterSnapshot{&c1s1, &c1s2, &c1s3}},
+				},
+			}
+
+			got, err := ListExpiredCluster(context.Background(), &tt.config, mockSvc, &rds.DBCluster{DBClusterIdentifier: aws.String("cluster-one")})
+
+			if (err != nil) != tt.want.err {
+				t.Errorf("ListExpiredCluster() error = %v, wantErr %v", err, tt.want.err)
+				return
+			}
+
+			if !reflect.DeepEqual(got, tt.want.result) {
+				t.Errorf("%v = %v, want %v", tt.name, got, tt.want.result)
+			}
+		})
+	}
+}
+
+func TestDeleteCluster(t *testing.T) {
+	t.Parallel()
+	c1s1 := rds.DBClusterSnapshot{DBClusterSnapshotIdentifier: aws.String("cluster-one-snap01-cf-ap-southeast-2")}
+	c1s1del := rds.DBClusterSnapshot{
+		DBClusterSnapshotIdentifier: aws.String("cluster-one-snap01"),
+		Status:                      aws.String("deleted"),
+	}
+
+	mockSvc := &mockRDSClient{
+		deleteDBClusterSnapshotOutput: &rds.DeleteDBClusterSnapshotOutput{DBClusterSnapshot: &c1s1del},
+	}
+
+	got, err := DeleteCluster(context.Background(), mockSvc, []*rds.DBClusterSnapshot{&c1s1})
+	if err != nil {
+		t.Errorf("DeleteCluster() error = %v", err)
+	}
+	if got != 1 {
+		t.Errorf("DeleteCluster() = %v, want 1", got)
+	}
+}
+
+func TestGetLatestCluster(t *testing.T) {
+	t.Parallel()
+	tone, _ := time.Parse(time.RFC822, "01 Jan 11 01:00 AEST")
+	ttwo, _ := time.Parse(time.RFC822, "02 Jan 12 02:00 AEST")
+	c1s1 := rds.DBClusterSnapshot{DBClusterSnapshotIdentifier: aws.String("cluster-one-snap01"), SnapshotCreateTime: &tone}
+	c1s2 := rds.DBClusterSnapshot{DBClusterSnapshotIdentifier: aws.String("cluster-one-snap02"), SnapshotCreateTime: &ttwo}
+
+	got, err := GetLatestCluster([]*rds.DBClusterSnapshot{&c1s2, &c1s1})
+	if err != nil {
+		t.Errorf("GetLatestCluster() error = %v", err)
+	}
+	if got != &c1s2 {
+		t.Errorf("GetLatestCluster() = %v, want %v", got, &c1s2)
+	}
+}
@@ -0,0 +1,146 @@
+package snapops
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+// Decision is the outcome of the retention policy for a single snapshot
+type Decision struct {
+	Snapshot string
+	Created  *time.Time
+	Keep     []string // The rules keeping the snapshot, e.g. "last", "age", "daily", "weekly", "monthly". Empty if expired
+}
+
+// Retain applies the retention policy in cfg to a list of snapshots, given oldest first, returning a Decision for each.
+// A snapshot is kept if any rule keeps it:
+// - last:    it is one of the latest cfg.MaxSnap
+// - age:     it is younger than cfg.MaxAgeDays
+// - daily:   it is the latest of its day, for the latest cfg.KeepDaily days with snapshots
+// - weekly:  it is the latest of its ISO week, for the latest cfg.KeepWeekly weeks with snapshots
+// - monthly: it is the latest of its month, for the latest cfg.KeepMonthly months with snapshots
+// - newest:  when the age or GFS rules are in use, the newest snapshot is always kept
+func Retain(cfg *wiring.Config, ids []string, created []*time.Time, now time.Time) []Decision {
+	d := make([]Decision, len(ids))
+	for i := range ids {
+		d[i] = Decision{Snapshot: ids[i], Created: created[i]}
+	}
+	if len(d) == 0 {
+		return d
+	}
+
+	keep := func(i int, rule string) {
+		d[i].Keep = append(d[i].Keep, rule)
+	}
+
+	for i := len(d) - cfg.MaxSnap; i < len(d); i++ {
+		if i >= 0 {
+			keep(i, "last")
+		}
+	}
+
+	if !cfg.AgeOrGFS() {
+		return d
+	}
+
+	keep(len(d)-1, "newest")
+
+	if cfg.MaxAgeDays > 0 {
+		cutoff := now.AddDate(0, 0, -cfg.MaxAgeDays)
+		for i := range d {
+			if d[i].Created == nil || d[i].Created.After(cutoff) {
+				keep(i, "age")
+			}
+		}
+	}
+
+	bucket(d, cfg.KeepDaily, "daily", func(t time.Time) string {
+		return t.UTC().Format("2006-01-02")
+	}, keep)
+	bucket(d, cfg.KeepWeekly, "weekly", func(t time.Time) string {
+		y, w := t.UTC().ISOWeek()
+		return fmt.Sprintf("%d-W%02d", y, w)
+	}, keep)
+	bucket(d, cfg.KeepMonthly, "monthly", func(t time.Time) string {
+		return t.UTC().Format("2006-01")
+	}, keep)
+
+	return d
+}
+
+// bucket keeps the latest snapshot in each of the latest n periods with snapshots. period names the period a time falls in
+func bucket(d []Decision, n int, rule string, period func(time.Time) string, keep func(int, string)) {
+	seen := make(map[string]bool)
+	for i := len(d) - 1; i >= 0 && len(seen) < n; i-- {
+		if d[i].Created == nil {
+			continue
+		}
+		p := period(*d[i].Created)
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		keep(i, rule)
+	}
+}
+
+// ListRetention applies the retention policy to the copies of an rds in the target region.
+// It returns the Decision for every copy, oldest first, and the expired copies.
+func ListRetention(ctx context.Context, cfg *wiring.Config, rdssessiontarget rdsiface.RDSAPI, instance *rds.DBInstance) ([]Decision, []*rds.DBSnapshot, error) {
+	all, err := List(ctx, rdssessiontarget, *instance.DBInstanceIdentifier)
+	if err != nil {
+		return nil, nil, err
+	}
+	ls := Sort(Copies(cfg, all))
+
+	ids := make([]string, len(ls))
+	created := make([]*time.Time, len(ls))
+	for i, s := range ls {
+		ids[i] = aws.StringValue(s.DBSnapshotIdentifier)
+		created[i] = s.SnapshotCreateTime
+	}
+
+	decisions := Retain(cfg, ids, created, time.Now())
+
+	var expired []*rds.DBSnapshot
+	for i, d := range decisions {
+		if len(d.Keep) == 0 {
+			expired = append(expired, ls[i])
+		}
+	}
+	return decisions, expired, nil
+}
+
+// ListRetentionCluster applies the retention policy to the copies of an Aurora cluster in the target region.
+// It returns the Decision for every copy, oldest first, and the expired copies.
+func ListRetentionCluster(ctx context.Context, cfg *wiring.Config, rdssessiontarget rdsiface.RDSAPI, cluster *rds.DBCluster) ([]Decision, []*rds.DBClusterSnapshot, error) {
+	all, err := ListCluster(ctx, rdssessiontarget, *cluster.DBClusterIdentifier)
+	if err != nil {
+		return nil, nil, err
+	}
+	ls := SortCluster(CopiesCluster(cfg, all))
+
+	ids := make([]string, len(ls))
+	created := make([]*time.Time, len(ls))
+	for i, s := range ls {
+		ids[i] = aws.StringValue(s.DBClusterSnapshotIdentifier)
+		created[i] = s.SnapshotCreateTime
+	}
+
+	decisions := Retain(cfg, ids, created, time.Now())
+
+	var expired []*rds.DBClusterSnapshot
+	for i, d := range decisions {
+		if len(d.Keep) == 0 {
+			expired = append(expired, ls[i])
+		}
+	}
+	return decisions, expired, nil
+}
@@ -0,0 +1,94 @@
+package snapops
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+func TestRetain(t *testing.T) {
+	t.Parallel()
+	at := func(s string) *time.Time {
+		r, _ := time.Parse(time.RFC3339, s)
+		return &r
+	}
+	now := *at("2019-03-31T12:00:00Z")
+	ids := []string{"a", "b", "c", "d", "e", "f", "g"}
+	created := []*time.Time{
+		at("2019-01-15T00:00:00Z"),
+		at("2019-02-10T00:00:00Z"),
+		at("2019-02-20T00:00:00Z"),
+		at("2019-03-25T00:00:00Z"),
+		at("2019-03-30T06:00:00Z"),
+		at("2019-03-30T18:00:00Z"),
+		at("2019-03-31T06:00:00Z"),
+	}
+
+	tests := []struct {
+		name string
+		cfg  wiring.Config
+		want [][]string // The rules keeping each snapshot, in order of ids
+	}{
+		{
+			name: "Retain_none",
+			cfg:  wiring.Config{},
+			want: [][]string{nil, nil, nil, nil, nil, nil, nil},
+		},
+		{
+			name: "Retain_last",
+			cfg:  wiring.Config{MaxSnap: 2},
+			want: [][]string{nil, nil, nil, nil, nil, {"last"}, {"last"}},
+		},
+		{
+			name: "Retain_age",
+			cfg:  wiring.Config{MaxAgeDays: 7},
+			want: [][]string{nil, nil, nil, {"age"}, {"age"}, {"age"}, {"newest", "age"}},
+		},
+		{
+			name: "Retain_daily",
+			cfg:  wiring.Config{KeepDaily: 2},
+			want: [][]string{nil, nil, nil, nil, nil, {"daily"}, {"newest", "daily"}},
+		},
+		{
+			name: "Retain_weekly",
+			cfg:  wiring.Config{KeepWeekly: 2},
+			want: [][]string{nil, nil, {"weekly"}, nil, nil, nil, {"newest", "weekly"}},
+		},
+		{
+			name: "Retain_monthly",
+			cfg:  wiring.Config{KeepMonthly: 3},
+			want: [][]string{{"monthly"}, nil, {"monthly"}, nil, nil, nil, {"newest", "monthly"}},
+		},
+		{
+			name: "Retain_age_1day",
+			cfg:  wiring.Config{MaxAgeDays: 1},
+			want: [][]string{nil, nil, nil, nil, nil, {"age"}, {"newest", "age"}},
+		},
+		{
+			name: "Retain_gfs",
+			cfg:  wiring.Config{MaxSnap: 1, KeepDaily: 2, KeepMonthly: 2},
+			want: [][]string{nil, nil, {"monthly"}, nil, nil, {"daily"}, {"last", "newest", "daily", "monthly"}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			decisions := Retain(&tt.cfg, ids, created, now)
+
+			var got [][]string
+			for i, d := range decisions {
+				if d.Snapshot != ids[i] || d.Created != created[i] {
+					t.Errorf("%v decision %d = %+v, for the wrong snapshot", tt.name, i, d)
+				}
+				got = append(got, d.Keep)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%v = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
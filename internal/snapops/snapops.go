@@ -1,6 +1,9 @@
 package snapops
 
 import (
+	"context"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -11,11 +14,8 @@ import (
 	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
 )
 
-// AntiRateLimit will be slept in key places, to preclude AWS API rate-limiting.
-const AntiRateLimit = (10 * time.Millisecond)
-
 // List will list all the snapshots for a given rds
-func List(rdssession rdsiface.RDSAPI, rdshost string) ([]*rds.DBSnapshot, error) {
+func List(ctx context.Context, rdssession rdsiface.RDSAPI, rdshost string) ([]*rds.DBSnapshot, error) {
 
 	input := &rds.DescribeDBSnapshotsInput{
 		DBInstanceIdentifier: aws.String(rdshost),
@@ -26,26 +26,108 @@ func List(rdssession rdsiface.RDSAPI, rdshost string) ([]*rds.DBSnapshot, error)
 
 	var res []*rds.DBSnapshot
 	pageNum := 0
-	err := rdssession.DescribeDBSnapshotsPages(input,
+	err := rdssession.DescribeDBSnapshotsPagesWithContext(ctx, input,
 		func(r *rds.DescribeDBSnapshotsOutput, lastPage bool) bool {
 			pageNum++
-			res = append(res, r.DBSnapshots...)
-			time.Sleep(AntiRateLimit)
+			for _, s := range r.DBSnapshots {
+				if !IsShare(aws.StringValue(s.DBSnapshotIdentifier)) {
+					res = append(res, s)
+				}
+			}
 			return pageNum <= 1000
 		})
 
 	return res, err
 }
 
+// ListCopying lists the snapshots being copied into the region from another region, by anything
+func ListCopying(ctx context.Context, rdssessiontarget rdsiface.RDSAPI) ([]*rds.DBSnapshot, error) {
+	all, err := ListCopied(ctx, rdssessiontarget)
+	if err != nil {
+		return nil, err
+	}
+	var res []*rds.DBSnapshot
+	for _, s := range all {
+		if InProgress(aws.StringValue(s.Status)) {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
+
+// ListCopied lists the snapshots copied into the region from another region, by anything, whatever their state
+func ListCopied(ctx context.Context, rdssessiontarget rdsiface.RDSAPI) ([]*rds.DBSnapshot, error) {
+
+	input := &rds.DescribeDBSnapshotsInput{
+		SnapshotType: aws.String("manual"),
+		MaxRecords:   aws.Int64(100),
+	}
+
+	var res []*rds.DBSnapshot
+	pageNum := 0
+	err := rdssessiontarget.DescribeDBSnapshotsPagesWithContext(ctx, input,
+		func(r *rds.DescribeDBSnapshotsOutput, lastPage bool) bool {
+			pageNum++
+			for _, s := range r.DBSnapshots {
+				if aws.StringValue(s.SourceRegion) != "" {
+					res = append(res, s)
+				}
+			}
+			return pageNum <= 1000
+		})
+
+	return res, err
+}
+
+// InProgress reports if a snapshot status is that of a snapshot still being created or copied
+func InProgress(status string) bool {
+	switch status {
+	case "creating", "copying", "pending":
+		return true
+	}
+	return false
+}
+
+// QuotaExceeded reports if err is AWS refusing a copy because of a snapshot or concurrent copy quota, which
+// is worth waiting out rather than failing the copy
+func QuotaExceeded(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	switch aerr.Code() {
+	case rds.ErrCodeSnapshotQuotaExceededFault:
+		return true
+	case "InvalidParameterValue", "InvalidParameterCombination":
+		// e.g. "Cannot copy more than 20 snapshots concurrently to region us-west-2"
+		return strings.Contains(strings.ToLower(aerr.Message()), "concurrent")
+	}
+	return false
+}
+
+// AlreadyExists reports if err is AWS refusing a copy because its target snapshot already exists, e.g. as the copy
+// was started by an event and a cycle at once
+func AlreadyExists(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	switch aerr.Code() {
+	case rds.ErrCodeDBSnapshotAlreadyExistsFault, rds.ErrCodeDBClusterSnapshotAlreadyExistsFault:
+		return true
+	}
+	return false
+}
+
 // Describe will describe a snapshot
-func Describe(rdssession rdsiface.RDSAPI, snap string) (*rds.DBSnapshot, error) {
+func Describe(ctx context.Context, rdssession rdsiface.RDSAPI, snap string) (*rds.DBSnapshot, error) {
 
 	input := &rds.DescribeDBSnapshotsInput{
 		DBSnapshotIdentifier: aws.String(snap),
 		MaxRecords:           aws.Int64(20), // AWS constraint; min 20
 	}
 
-	res, err := rdssession.DescribeDBSnapshots(input)
+	res, err := rdssession.DescribeDBSnapshotsWithContext(ctx, input)
 	if err != nil {
 		err, ok := err.(awserr.Error)
 		if ok && err.Code() == rds.ErrCodeDBSnapshotNotFoundFault {
@@ -62,19 +144,20 @@ func Describe(rdssession rdsiface.RDSAPI, snap string) (*rds.DBSnapshot, error)
 }
 
 // PullSnapShot pull a copy of an AWS rds snapshot from a remote region. It is not blocking.
-func PullSnapShot(cfg *wiring.Config, rdssession rdsiface.RDSAPI, arn *string, targetsnapshotname string) (*rds.CopyDBSnapshotOutput, error) {
+func PullSnapShot(ctx context.Context, cfg *wiring.Config, rdssession rdsiface.RDSAPI, arn *string, targetsnapshotname string, tags []*rds.Tag) (*rds.CopyDBSnapshotOutput, error) {
 	input := &rds.CopyDBSnapshotInput{
 		SourceDBSnapshotIdentifier: aws.String(*arn),
 		TargetDBSnapshotIdentifier: aws.String(targetsnapshotname),
 		DestinationRegion:          aws.String(cfg.TargetRegion),
 		KmsKeyId:                   aws.String(cfg.TargetKMS),
+		Tags:                       tags,
 	}
-	result, err := rdssession.CopyDBSnapshot(input)
+	result, err := rdssession.CopyDBSnapshotWithContext(ctx, input)
 	return result, err
 }
 
 // PullEncryptedSnapShot pulls an encrypted AWS rds snapshot from a remote region. It is not blocking.
-func PullEncryptedSnapShot(cfg *wiring.Config, rdssessionsource rdsiface.RDSAPI, rdssessiontarget rdsiface.RDSAPI, arn *string, targetsnapshotname string) (*rds.CopyDBSnapshotOutput, error) {
+func PullEncryptedSnapShot(ctx context.Context, cfg *wiring.Config, rdssessionsource rdsiface.RDSAPI, rdssessiontarget rdsiface.RDSAPI, arn *string, targetsnapshotname string, tags []*rds.Tag) (*rds.CopyDBSnapshotOutput, error) {
 	// Build the PreSignedUrl containing the CopyDBSnapshot API
 	inputps := &rds.CopyDBSnapshotInput{
 		SourceDBSnapshotIdentifier: aws.String(*arn),
@@ -95,33 +178,126 @@ func PullEncryptedSnapShot(cfg *wiring.Config, rdssessionsource rdsiface.RDSAPI,
 		DestinationRegion:          aws.String(cfg.TargetRegion),
 		KmsKeyId:                   aws.String(cfg.TargetKMS),
 		PreSignedUrl:               aws.String(psurl),
+		Tags:                       tags,
 	}
-	result, err := rdssessiontarget.CopyDBSnapshot(input)
+	result, err := rdssessiontarget.CopyDBSnapshotWithContext(ctx, input)
 	return result, err
 }
 
-// ListExpired lists the snapshots for an rds, that are considered expired.
-func ListExpired(cfg *wiring.Config, rdssessiontarget rdsiface.RDSAPI, instance *rds.DBInstance) ([]*rds.DBSnapshot, error) {
-	ls, err := List(rdssessiontarget, *instance.DBInstanceIdentifier)
+// Share shares a snapshot with another AWS account, so that account may copy it.
+// Only manual snapshots may be shared.
+func Share(ctx context.Context, rdssessionsource rdsiface.RDSAPI, snap, account string) error {
+	input := &rds.ModifyDBSnapshotAttributeInput{
+		AttributeName:        aws.String("restore"),
+		DBSnapshotIdentifier: aws.String(snap),
+		ValuesToAdd:          []*string{aws.String(account)},
+	}
+	_, err := rdssessionsource.ModifyDBSnapshotAttributeWithContext(ctx, input)
+	return err
+}
+
+// Unshare stops sharing a snapshot with another AWS account
+func Unshare(ctx context.Context, rdssessionsource rdsiface.RDSAPI, snap, account string) error {
+	input := &rds.ModifyDBSnapshotAttributeInput{
+		AttributeName:        aws.String("restore"),
+		DBSnapshotIdentifier: aws.String(snap),
+		ValuesToRemove:       []*string{aws.String(account)},
+	}
+	_, err := rdssessionsource.ModifyDBSnapshotAttributeWithContext(ctx, input)
+	return err
+}
+
+// Tags on each copy this tool starts, until it has finished with the copy. They let a later run pick up a copy
+// handed off, or left, in progress, whatever state it has since reached.
+const (
+	SourceTag = "rds-snapshot-copier:source" // The source snapshot identifier, in full
+	ShareTag  = "rds-snapshot-copier:share"  // The manual copy of an automated source snapshot shared to copy it, if any
+)
+
+// Tags returns the tags of an rds resource, by key
+func Tags(ctx context.Context, rdssession rdsiface.RDSAPI, arn string) (map[string]string, error) {
+	out, err := rdssession.ListTagsForResourceWithContext(ctx, &rds.ListTagsForResourceInput{ResourceName: aws.String(arn)})
 	if err != nil {
 		return nil, err
 	}
+	tags := make(map[string]string)
+	for _, t := range out.TagList {
+		tags[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+	}
+	return tags, nil
+}
 
-	// if have less (or equal) to cfg.MaxSnap; Just return
-	if len(ls) <= cfg.MaxSnap {
-		return nil, nil
+// Untag removes SourceTag and ShareTag from a copy, once this tool has finished with it
+func Untag(ctx context.Context, rdssessiontarget rdsiface.RDSAPI, arn string) error {
+	input := &rds.RemoveTagsFromResourceInput{
+		ResourceName: aws.String(arn),
+		TagKeys:      aws.StringSlice([]string{SourceTag, ShareTag}),
+	}
+	_, err := rdssessiontarget.RemoveTagsFromResourceWithContext(ctx, input)
+	return err
+}
+
+// ShareName is the name of the manual copy of an automated snapshot, made in the source region to be shared with the
+// account copying it to targetRegion. Only manual snapshots may be shared.
+func ShareName(snap, targetRegion string) string {
+	return strings.Replace(snap, "rds:", "", -1) + "-share-" + targetRegion
+}
+
+// IsShare reports if a snapshot is a manual copy of an automated snapshot made by this tool to share it, see ShareName.
+// These are never copied themselves.
+func IsShare(snap string) bool {
+	i := strings.LastIndex(snap, "-share-")
+	return i >= 0 && wiring.IsRegion(snap[i+len("-share-"):])
+}
+
+// ListShares lists the manual copies of automated snapshots made by this tool to share them with the account copying
+// them to targetRegion, see ShareName, whatever their state
+func ListShares(ctx context.Context, rdssessionsource rdsiface.RDSAPI, targetRegion string) ([]*rds.DBSnapshot, error) {
+
+	input := &rds.DescribeDBSnapshotsInput{
+		SnapshotType: aws.String("manual"),
+		MaxRecords:   aws.Int64(100),
 	}
 
-	expired, err := GetSlice(ls, 0, (len(ls) - cfg.MaxSnap))
+	var res []*rds.DBSnapshot
+	pageNum := 0
+	err := rdssessionsource.DescribeDBSnapshotsPagesWithContext(ctx, input,
+		func(r *rds.DescribeDBSnapshotsOutput, lastPage bool) bool {
+			pageNum++
+			for _, s := range r.DBSnapshots {
+				if strings.HasSuffix(aws.StringValue(s.DBSnapshotIdentifier), "-share-"+targetRegion) {
+					res = append(res, s)
+				}
+			}
+			return pageNum <= 1000
+		})
+
+	return res, err
+}
+
+// CopyLocal copies a snapshot within its region, e.g. an automated snapshot to a manual one. It is not blocking.
+func CopyLocal(ctx context.Context, rdssession rdsiface.RDSAPI, snap, targetsnapshotname string, tags []*rds.Tag) (*rds.DBSnapshot, error) {
+	input := &rds.CopyDBSnapshotInput{
+		SourceDBSnapshotIdentifier: aws.String(snap),
+		TargetDBSnapshotIdentifier: aws.String(targetsnapshotname),
+		Tags:                       tags,
+	}
+	result, err := rdssession.CopyDBSnapshotWithContext(ctx, input)
 	if err != nil {
 		return nil, err
 	}
+	return result.DBSnapshot, nil
+}
 
-	return expired, nil
+// ListExpired lists the snapshots for an rds, that are considered expired.
+// Only snapshots copied by this tool (see TargetName) are considered. See Retain for the retention rules.
+func ListExpired(ctx context.Context, cfg *wiring.Config, rdssessiontarget rdsiface.RDSAPI, instance *rds.DBInstance) ([]*rds.DBSnapshot, error) {
+	_, expired, err := ListRetention(ctx, cfg, rdssessiontarget, instance)
+	return expired, err
 }
 
 // Delete will delete a list of snapshots.
-func Delete(rdssessiontarget rdsiface.RDSAPI, snaps []*rds.DBSnapshot) (int, error) {
+func Delete(ctx context.Context, rdssessiontarget rdsiface.RDSAPI, snaps []*rds.DBSnapshot) (int, error) {
 	if snaps == nil {
 		return 0, nil
 	}
@@ -131,7 +307,7 @@ func Delete(rdssessiontarget rdsiface.RDSAPI, snaps []*rds.DBSnapshot) (int, err
 		del := &rds.DeleteDBSnapshotInput{
 			DBSnapshotIdentifier: i.DBSnapshotIdentifier,
 		}
-		r, e := rdssessiontarget.DeleteDBSnapshot(del)
+		r, e := rdssessiontarget.DeleteDBSnapshotWithContext(ctx, del)
 		if e != nil {
 			return 0, e
 		}
@@ -140,17 +316,44 @@ func Delete(rdssessiontarget rdsiface.RDSAPI, snaps []*rds.DBSnapshot) (int, err
 			count++
 		}
 
-		time.Sleep(AntiRateLimit)
 	}
 	return count, nil
 }
 
+// Sort returns a copy of a list of snapshots, ordered oldest first by SnapshotCreateTime.
+// AWS does not guarantee the order snapshots are listed in. Snapshots without a create time (still
+// being created) are newest, and ties are ordered by identifier.
+func Sort(all []*rds.DBSnapshot) []*rds.DBSnapshot {
+	sorted := make([]*rds.DBSnapshot, len(all))
+	copy(sorted, all)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return older(sorted[i].SnapshotCreateTime, sorted[j].SnapshotCreateTime, aws.StringValue(sorted[i].DBSnapshotIdentifier), aws.StringValue(sorted[j].DBSnapshotIdentifier))
+	})
+	return sorted
+}
+
+// older reports if snapshot i was created before snapshot j
+func older(ti, tj *time.Time, idi, idj string) bool {
+	switch {
+	case ti == nil && tj == nil:
+		return idi < idj
+	case ti == nil:
+		return false
+	case tj == nil:
+		return true
+	case ti.Equal(*tj):
+		return idi < idj
+	}
+	return ti.Before(*tj)
+}
+
 // GetSlice returns a "slice" of a list of snapshots, ordered by date.
 // TODO: Yuck the following sucks and needs cleanup!
 func GetSlice(all []*rds.DBSnapshot, start, num int) ([]*rds.DBSnapshot, error) {
 	if len(all) == 0 {
 		return nil, nil
 	}
+	all = Sort(all)
 	lena := len(all)
 	var end int
 	if start >= 0 {
@@ -182,3 +385,93 @@ func GetLatest(all []*rds.DBSnapshot) (*rds.DBSnapshot, error) {
 	}
 	return r[0], nil
 }
+
+// TargetName is the name a snapshot is given in the target region
+func TargetName(cfg *wiring.Config, snap string) string {
+	return strings.Replace((snap + "-cf-" + cfg.SourceRegion), "rds:", "", -1)
+}
+
+// IsCopy reports if a snapshot in the target region was copied from the source region by this tool
+func IsCopy(cfg *wiring.Config, snap string) bool {
+	return strings.HasSuffix(snap, "-cf-"+cfg.SourceRegion)
+}
+
+// Copies returns the snapshots that were copied from the source region by this tool
+func Copies(cfg *wiring.Config, all []*rds.DBSnapshot) []*rds.DBSnapshot {
+	var copies []*rds.DBSnapshot
+	for _, s := range all {
+		if IsCopy(cfg, aws.StringValue(s.DBSnapshotIdentifier)) {
+			copies = append(copies, s)
+		}
+	}
+	return copies
+}
+
+// Missing returns the available source snapshots taken after since that have no copy in the target, oldest
+// first. Those the target's retention rules would delete as soon as they were copied are left out, so snapshots
+// housekept from the target are not copied again.
+func Missing(cfg *wiring.Config, source, target []*rds.DBSnapshot, since time.Time) []*rds.DBSnapshot {
+	copies := make(map[string]*time.Time)
+	for _, t := range Copies(cfg, target) {
+		copies[*t.DBSnapshotIdentifier] = t.SnapshotCreateTime
+	}
+
+	candidates := make(map[string]*time.Time)
+	for _, s := range source {
+		if aws.StringValue(s.Status) != "available" || s.SnapshotCreateTime == nil || !s.SnapshotCreateTime.After(since) {
+			continue
+		}
+		if _, ok := copies[TargetName(cfg, *s.DBSnapshotIdentifier)]; ok {
+			continue
+		}
+		candidates[TargetName(cfg, *s.DBSnapshotIdentifier)] = s.SnapshotCreateTime
+	}
+
+	keep := wouldKeep(cfg, copies, candidates, time.Now())
+	var missing []*rds.DBSnapshot
+	for _, s := range source {
+		if keep[TargetName(cfg, *s.DBSnapshotIdentifier)] {
+			missing = append(missing, s)
+		}
+	}
+
+	if missing == nil {
+		return nil
+	}
+	return Sort(missing)
+}
+
+// wouldKeep returns the candidates, by target name, that the retention rules would keep were they copied
+// alongside the copies already in the target. The rest would be housekept straight away.
+func wouldKeep(cfg *wiring.Config, copies, candidates map[string]*time.Time, now time.Time) map[string]bool {
+	keep := make(map[string]bool)
+	if !cfg.Retention() {
+		for id := range candidates {
+			keep[id] = true
+		}
+		return keep
+	}
+
+	created := make(map[string]*time.Time)
+	ids := make([]string, 0, len(copies)+len(candidates))
+	for _, m := range []map[string]*time.Time{copies, candidates} {
+		for id, t := range m {
+			created[id] = t
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return older(created[ids[i]], created[ids[j]], ids[i], ids[j])
+	})
+	times := make([]*time.Time, len(ids))
+	for i, id := range ids {
+		times[i] = created[id]
+	}
+
+	for _, d := range Retain(cfg, ids, times, now) {
+		if _, ok := candidates[d.Snapshot]; ok && len(d.Keep) > 0 {
+			keep[d.Snapshot] = true
+		}
+	}
+	return keep
+}
@@ -1,11 +1,14 @@
 package snapops
 
 import (
+	"context"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/rds"
 	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
 
@@ -14,9 +17,12 @@ import (
 
 func TestGetSlice(t *testing.T) {
 	t.Parallel()
-	s1 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("one")}
-	s2 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("two")}
-	s3 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("three")}
+	tone, _ := time.Parse(time.RFC822, "01 Jan 11 01:00 AEST")
+	ttwo, _ := time.Parse(time.RFC822, "02 Jan 12 02:00 AEST")
+	tthree, _ := time.Parse(time.RFC822, "03 Jan 13 03:00 AEST")
+	s1 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("one"), SnapshotCreateTime: &tone}
+	s2 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("two"), SnapshotCreateTime: &ttwo}
+	s3 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("three"), SnapshotCreateTime: &tthree}
 
 	type args struct {
 		all   []*rds.DBSnapshot
@@ -125,8 +131,10 @@ func TestGetSlice(t *testing.T) {
 
 func TestGetLatest(t *testing.T) {
 	t.Parallel()
-	s1 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("one")}
-	s2 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("two")}
+	tone, _ := time.Parse(time.RFC822, "01 Jan 11 01:00 AEST")
+	ttwo, _ := time.Parse(time.RFC822, "02 Jan 12 02:00 AEST")
+	s1 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("one"), SnapshotCreateTime: &tone}
+	s2 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("two"), SnapshotCreateTime: &ttwo}
 
 	type args struct {
 		all   []*rds.DBSnapshot
@@ -154,6 +162,18 @@ func TestGetLatest(t *testing.T) {
 				err:    false,
 			},
 		},
+		{
+			name: "getlatest_out_of_order",
+			args: args{
+				all:   []*rds.DBSnapshot{&s2, &s1},
+				start: -1,
+				num:   1,
+			},
+			want: want{
+				result: &s2,
+				err:    false,
+			},
+		},
 		{
 			name: "getlatest_empty",
 			args: args{
@@ -218,6 +238,22 @@ func TestList(t *testing.T) {
 				DBSnapshots: []*rds.DBSnapshot{&i1s1},
 			},
 		},
+		{
+			name: "getsnapshots_share_copy_skipped",
+			args: args{
+				dbinstance: "dbinstance-one",
+			},
+			want: want{
+				result: []*rds.DBSnapshot{&i1s1},
+				err:    false,
+			},
+			awsmockresult: &rds.DescribeDBSnapshotsOutput{
+				DBSnapshots: []*rds.DBSnapshot{&i1s1, {
+					DBInstanceIdentifier: aws.String("dbinstance-one"),
+					DBSnapshotIdentifier: aws.String("dbinstance-one-snap00-share-us-west-2"),
+				}},
+			},
+		},
 		{
 			name: "getsnapshots_nonefound",
 			args: args{
@@ -237,7 +273,7 @@ func TestList(t *testing.T) {
 				describeDBSnapShotOutput: tt.awsmockresult,
 			}
 
-			got, err := List(mockSvc, tt.args.dbinstance)
+			got, err := List(context.Background(), mockSvc, tt.args.dbinstance)
 
 			if (err != nil) != tt.want.err {
 				t.Errorf("List() error = %v, wantErr %v", err, tt.want.err)
@@ -252,6 +288,74 @@ func TestList(t *testing.T) {
 	}
 }
 
+func TestListCopying(t *testing.T) {
+	t.Parallel()
+	copying := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("one-copy"), SourceRegion: aws.String("ap-southeast-2"), Status: aws.String("copying")}
+	pending := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("two-copy"), SourceRegion: aws.String("ap-southeast-2"), Status: aws.String("pending")}
+	copied := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("three-copy"), SourceRegion: aws.String("ap-southeast-2"), Status: aws.String("available")}
+	local := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("four"), Status: aws.String("creating")}
+
+	mockSvc := &mockRDSClient{
+		describeDBSnapShotPages: []*rds.DescribeDBSnapshotsOutput{
+			{DBSnapshots: []*rds.DBSnapshot{&copying, &copied}},
+			{DBSnapshots: []*rds.DBSnapshot{&local, &pending}},
+		},
+	}
+
+	got, err := ListCopying(context.Background(), mockSvc)
+	if err != nil {
+		t.Fatalf("ListCopying() error = %v", err)
+	}
+	if want := []*rds.DBSnapshot{&copying, &pending}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListCopying() = %v, want %v", got, want)
+	}
+}
+
+func TestQuotaExceeded(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"QuotaExceeded_snapshots", awserr.New(rds.ErrCodeSnapshotQuotaExceededFault, "", nil), true},
+		{"QuotaExceeded_concurrent_copies", awserr.New("InvalidParameterValue", "Cannot copy more than 20 snapshots concurrently to region us-west-2", nil), true},
+		{"QuotaExceeded_bad_parameter", awserr.New("InvalidParameterValue", "Invalid snapshot identifier", nil), false},
+		{"QuotaExceeded_already_exists", awserr.New(rds.ErrCodeDBSnapshotAlreadyExistsFault, "", nil), false},
+		{"QuotaExceeded_nil", nil, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := QuotaExceeded(tt.err); got != tt.want {
+				t.Errorf("%v = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlreadyExists(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"AlreadyExists_snapshot", awserr.New(rds.ErrCodeDBSnapshotAlreadyExistsFault, "", nil), true},
+		{"AlreadyExists_cluster_snapshot", awserr.New(rds.ErrCodeDBClusterSnapshotAlreadyExistsFault, "", nil), true},
+		{"AlreadyExists_quota", awserr.New(rds.ErrCodeSnapshotQuotaExceededFault, "", nil), false},
+		{"AlreadyExists_nil", nil, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AlreadyExists(tt.err); got != tt.want {
+				t.Errorf("%v = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestPullSnapShot(t *testing.T) {
 	t.Parallel()
 	sres01 := rds.DBSnapshot{
@@ -298,7 +402,7 @@ func TestPullSnapShot(t *testing.T) {
 				copyDBSnapshotOutput: tt.awsmockresult,
 			}
 
-			got, err := PullSnapShot(&tt.args.config, mockSvc, &tt.args.arn, tt.args.targetsnapshotname)
+			got, err := PullSnapShot(context.Background(), &tt.args.config, mockSvc, &tt.args.arn, tt.args.targetsnapshotname, nil)
 
 			if (err != nil) != tt.want.err {
 				t.Errorf("List() error = %v, wantErr %v", err, tt.want.err)
@@ -321,17 +425,22 @@ func TestListExpired(t *testing.T) {
 
 	sres01 := rds.DBSnapshot{
 		DBInstanceIdentifier: aws.String("dbinstance-one"),
-		DBSnapshotIdentifier: aws.String("dbinstance-one-snap01"),
+		DBSnapshotIdentifier: aws.String("dbinstance-one-snap01-cf-ap-southeast-2"),
 		SnapshotCreateTime:   &tone,
 	}
 	sres02 := rds.DBSnapshot{
 		DBInstanceIdentifier: aws.String("dbinstance-one"),
-		DBSnapshotIdentifier: aws.String("dbinstance-one-snap02"),
+		DBSnapshotIdentifier: aws.String("dbinstance-one-snap02-cf-ap-southeast-2"),
 		SnapshotCreateTime:   &ttwo,
 	}
+	notacopy := rds.DBSnapshot{
+		DBInstanceIdentifier: aws.String("dbinstance-one"),
+		DBSnapshotIdentifier: aws.String("rds:dbinstance-one-snap00"),
+		SnapshotCreateTime:   &tone,
+	}
 	sres03 := rds.DBSnapshot{
 		DBInstanceIdentifier: aws.String("dbinstance-one"),
-		DBSnapshotIdentifier: aws.String("dbinstance-one-snap03"),
+		DBSnapshotIdentifier: aws.String("dbinstance-one-snap03-cf-ap-southeast-2"),
 		SnapshotCreateTime:   &tthree,
 	}
 
@@ -347,14 +456,55 @@ func TestListExpired(t *testing.T) {
 	tests := []struct {
 		args          args
 		awsmockresult *rds.DescribeDBSnapshotsOutput
+		awsmockpages  []*rds.DescribeDBSnapshotsOutput
 		name          string
 		want          want
 	}{
+		{
+			name: "ListExpired_out_of_order_pages",
+			args: args{
+				config: wiring.Config{
+					MaxSnap:      1,
+					SourceRegion: "ap-southeast-2",
+				},
+				instance: &rds.DBInstance{
+					DBInstanceIdentifier: aws.String("one"),
+				},
+			},
+			want: want{
+				result: []*rds.DBSnapshot{&sres01, &sres02},
+				err:    false,
+			},
+			awsmockpages: []*rds.DescribeDBSnapshotsOutput{
+				{DBSnapshots: []*rds.DBSnapshot{&sres03, &sres01}},
+				{DBSnapshots: []*rds.DBSnapshot{&sres02}},
+			},
+		},
+		{
+			name: "ListExpired_only_copies",
+			args: args{
+				config: wiring.Config{
+					MaxSnap:      1,
+					SourceRegion: "ap-southeast-2",
+				},
+				instance: &rds.DBInstance{
+					DBInstanceIdentifier: aws.String("one"),
+				},
+			},
+			want: want{
+				result: []*rds.DBSnapshot{&sres01, &sres02},
+				err:    false,
+			},
+			awsmockresult: &rds.DescribeDBSnapshotsOutput{
+				DBSnapshots: []*rds.DBSnapshot{&notacopy, &sres01, &sres02, &sres03},
+			},
+		},
 		{
 			name: "ListExpired_retain1_expire2",
 			args: args{
 				config: wiring.Config{
-					MaxSnap: 1,
+					MaxSnap:      1,
+					SourceRegion: "ap-southeast-2",
 				},
 				instance: &rds.DBInstance{
 					DBInstanceIdentifier: aws.String("one"),
@@ -372,7 +522,8 @@ func TestListExpired(t *testing.T) {
 			name: "ListExpired_retain2_expire1",
 			args: args{
 				config: wiring.Config{
-					MaxSnap: 2,
+					MaxSnap:      2,
+					SourceRegion: "ap-southeast-2",
 				},
 				instance: &rds.DBInstance{
 					DBInstanceIdentifier: aws.String("one"),
@@ -390,7 +541,8 @@ func TestListExpired(t *testing.T) {
 			name: "ListExpired_retain3_expire0-i",
 			args: args{
 				config: wiring.Config{
-					MaxSnap: 3,
+					MaxSnap:      3,
+					SourceRegion: "ap-southeast-2",
 				},
 				instance: &rds.DBInstance{
 					DBInstanceIdentifier: aws.String("one"),
@@ -408,7 +560,8 @@ func TestListExpired(t *testing.T) {
 			name: "ListExpired_retain3_expire0-ii",
 			args: args{
 				config: wiring.Config{
-					MaxSnap: 4,
+					MaxSnap:      4,
+					SourceRegion: "ap-southeast-2",
 				},
 				instance: &rds.DBInstance{
 					DBInstanceIdentifier: aws.String("one"),
@@ -426,7 +579,8 @@ func TestListExpired(t *testing.T) {
 			name: "ListExpired_retain0_expire3",
 			args: args{
 				config: wiring.Config{
-					MaxSnap: 0,
+					MaxSnap:      0,
+					SourceRegion: "ap-southeast-2",
 				},
 				instance: &rds.DBInstance{
 					DBInstanceIdentifier: aws.String("one"),
@@ -447,9 +601,10 @@ func TestListExpired(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockSvc := &mockRDSClient{
 				describeDBSnapShotOutput: tt.awsmockresult,
+				describeDBSnapShotPages:  tt.awsmockpages,
 			}
 
-			got, err := ListExpired(&tt.args.config, mockSvc, tt.args.instance)
+			got, err := ListExpired(context.Background(), &tt.args.config, mockSvc, tt.args.instance)
 
 			if (err != nil) != tt.want.err {
 				t.Errorf("List() error = %v, wantErr %v", err, tt.want.err)
@@ -530,7 +685,7 @@ func TestDelete(t *testing.T) {
 				deleteDBSnapshotOutput: tt.awsmockresult,
 			}
 
-			got, err := Delete(mockSvc, tt.args.snaps)
+			got, err := Delete(context.Background(), mockSvc, tt.args.snaps)
 
 			if (err != nil) != tt.want.err {
 				t.Errorf("List() error = %v, wantErr %v", err, tt.want.err)
@@ -545,31 +700,275 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestShare(t *testing.T) {
+	t.Parallel()
+	mockSvc := &mockRDSClient{}
+
+	if err := Share(context.Background(), mockSvc, "dbinstance-one-snap01", "123456789012"); err != nil {
+		t.Errorf("Share() error = %v", err)
+	}
+	want := &rds.ModifyDBSnapshotAttributeInput{
+		AttributeName:        aws.String("restore"),
+		DBSnapshotIdentifier: aws.String("dbinstance-one-snap01"),
+		ValuesToAdd:          []*string{aws.String("123456789012")},
+	}
+	if !reflect.DeepEqual(mockSvc.modifyDBSnapshotAttributeInput, want) {
+		t.Errorf("Share() = %v, want %v", mockSvc.modifyDBSnapshotAttributeInput, want)
+	}
+
+	if err := Unshare(context.Background(), mockSvc, "dbinstance-one-snap01", "123456789012"); err != nil {
+		t.Errorf("Unshare() error = %v", err)
+	}
+	want = &rds.ModifyDBSnapshotAttributeInput{
+		AttributeName:        aws.String("restore"),
+		DBSnapshotIdentifier: aws.String("dbinstance-one-snap01"),
+		ValuesToRemove:       []*string{aws.String("123456789012")},
+	}
+	if !reflect.DeepEqual(mockSvc.modifyDBSnapshotAttributeInput, want) {
+		t.Errorf("Unshare() = %v, want %v", mockSvc.modifyDBSnapshotAttributeInput, want)
+	}
+}
+
+func TestSort(t *testing.T) {
+	t.Parallel()
+	tone, _ := time.Parse(time.RFC822, "01 Jan 11 01:00 AEST")
+	ttwo, _ := time.Parse(time.RFC822, "02 Jan 12 02:00 AEST")
+
+	s1 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("rds:one-a"), SnapshotCreateTime: &tone}
+	s2 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("rds:one-b"), SnapshotCreateTime: &ttwo}
+	s2tie := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("rds:one-c"), SnapshotCreateTime: &ttwo}
+	creating := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("rds:one-d")}
+
+	tests := []struct {
+		name string
+		all  []*rds.DBSnapshot
+		want []*rds.DBSnapshot
+	}{
+		{
+			name: "Sort_in_order",
+			all:  []*rds.DBSnapshot{&s1, &s2},
+			want: []*rds.DBSnapshot{&s1, &s2},
+		},
+		{
+			name: "Sort_reversed",
+			all:  []*rds.DBSnapshot{&s2, &s1},
+			want: []*rds.DBSnapshot{&s1, &s2},
+		},
+		{
+			name: "Sort_tie_by_identifier",
+			all:  []*rds.DBSnapshot{&s2tie, &s1, &s2},
+			want: []*rds.DBSnapshot{&s1, &s2, &s2tie},
+		},
+		{
+			name: "Sort_creating_is_newest",
+			all:  []*rds.DBSnapshot{&creating, &s2, &s1},
+			want: []*rds.DBSnapshot{&s1, &s2, &creating},
+		},
+		{
+			name: "Sort_empty",
+			all:  []*rds.DBSnapshot{},
+			want: []*rds.DBSnapshot{},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			in := make([]*rds.DBSnapshot, len(tt.all))
+			copy(in, tt.all)
+
+			got := Sort(tt.all)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%v = %v, want %v", tt.name, got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.all, in) {
+				t.Errorf("%v modified its input", tt.name)
+			}
+		})
+	}
+}
+
+func TestTargetName(t *testing.T) {
+	t.Parallel()
+	cfg := wiring.Config{SourceRegion: "ap-southeast-2"}
+
+	if got := TargetName(&cfg, "rds:dbinstance-one-2019-03-01-10-00"); got != "dbinstance-one-2019-03-01-10-00-cf-ap-southeast-2" {
+		t.Errorf("TargetName() = %v", got)
+	}
+}
+
+func TestShareName(t *testing.T) {
+	t.Parallel()
+
+	got := ShareName("rds:dbinstance-one-2019-03-01-10-00", "us-west-2")
+	if got != "dbinstance-one-2019-03-01-10-00-share-us-west-2" {
+		t.Errorf("ShareName() = %v", got)
+	}
+	if !IsShare(got) {
+		t.Errorf("IsShare(%v) = false, want true", got)
+	}
+	if IsShare("my-share-backup") {
+		t.Errorf("IsShare(%v) = true, want false", "my-share-backup")
+	}
+}
+
+func TestMissing(t *testing.T) {
+	t.Parallel()
+	cfg := wiring.Config{SourceRegion: "ap-southeast-2"}
+	now := time.Now()
+	t1 := now.Add(-72 * time.Hour)
+	t2 := now.Add(-48 * time.Hour)
+	t3 := now.Add(-24 * time.Hour)
+	t4 := now.Add(-1 * time.Hour)
+
+	s1 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("rds:one-s1"), SnapshotCreateTime: &t1, Status: aws.String("available")}
+	s2 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("rds:one-s2"), SnapshotCreateTime: &t2, Status: aws.String("available")}
+	s3 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("rds:one-s3"), SnapshotCreateTime: &t3, Status: aws.String("available")}
+	s4 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("rds:one-s4"), SnapshotCreateTime: &t4, Status: aws.String("creating")}
+	c1 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("one-s1-cf-ap-southeast-2"), SnapshotCreateTime: &t1}
+	c2 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("one-s2-cf-ap-southeast-2"), SnapshotCreateTime: &t2}
+	c3 := rds.DBSnapshot{DBSnapshotIdentifier: aws.String("one-s3-cf-ap-southeast-2"), SnapshotCreateTime: &t3}
+
+	tests := []struct {
+		name   string
+		keep   int
+		source []*rds.DBSnapshot
+		target []*rds.DBSnapshot
+		since  time.Time
+		want   []*rds.DBSnapshot
+	}{
+		{
+			name:   "Missing_none_copied_oldest_first",
+			source: []*rds.DBSnapshot{&s3, &s1, &s2},
+			since:  now.Add(-96 * time.Hour),
+			want:   []*rds.DBSnapshot{&s1, &s2, &s3},
+		},
+		{
+			name:   "Missing_outside_lookback",
+			source: []*rds.DBSnapshot{&s1, &s2, &s3},
+			since:  now.Add(-50 * time.Hour),
+			want:   []*rds.DBSnapshot{&s2, &s3},
+		},
+		{
+			name:   "Missing_not_yet_available",
+			source: []*rds.DBSnapshot{&s1, &s2, &s3, &s4},
+			target: []*rds.DBSnapshot{&c1, &c2},
+			since:  now.Add(-96 * time.Hour),
+			want:   []*rds.DBSnapshot{&s3},
+		},
+		{
+			name:   "Missing_gap_before_last_copied",
+			source: []*rds.DBSnapshot{&s1, &s2},
+			target: []*rds.DBSnapshot{&c2},
+			since:  now.Add(-7 * 24 * time.Hour),
+			want:   []*rds.DBSnapshot{&s1},
+		},
+		{
+			name:   "Missing_all_copied",
+			source: []*rds.DBSnapshot{&s1, &s2},
+			target: []*rds.DBSnapshot{&c1, &c2},
+			since:  now.Add(-96 * time.Hour),
+			want:   nil,
+		},
+		{
+			name:   "Missing_housekept_not_recopied",
+			keep:   2,
+			source: []*rds.DBSnapshot{&s1, &s2, &s3},
+			target: []*rds.DBSnapshot{&c2, &c3},
+			since:  now.Add(-96 * time.Hour),
+			want:   nil,
+		},
+		{
+			name:   "Missing_gap_kept_by_retention",
+			keep:   3,
+			source: []*rds.DBSnapshot{&s1, &s2, &s3},
+			target: []*rds.DBSnapshot{&c1, &c3},
+			since:  now.Add(-96 * time.Hour),
+			want:   []*rds.DBSnapshot{&s2},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cfg
+			cfg.MaxSnap = tt.keep
+			got := Missing(&cfg, tt.source, tt.target, tt.since)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%v = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
 // Defines a mock struct to be used for unit tests
 type mockRDSClient struct {
 	rdsiface.RDSAPI
 	describeDBSnapShotOutput *rds.DescribeDBSnapshotsOutput
+	describeDBSnapShotPages  []*rds.DescribeDBSnapshotsOutput // if set, returned by DescribeDBSnapshotsPages in place of describeDBSnapShotOutput
 	copyDBSnapshotOutput     *rds.CopyDBSnapshotOutput
 	deleteDBSnapshotOutput   *rds.DeleteDBSnapshotOutput
+
+	modifyDBSnapshotAttributeInput *rds.ModifyDBSnapshotAttributeInput // records the last call
+
+	describeDBClusterSnapshotsOutput []*rds.DescribeDBClusterSnapshotsOutput // one per page
+	copyDBClusterSnapshotOutput      *rds.CopyDBClusterSnapshotOutput
+	deleteDBClusterSnapshotOutput    *rds.DeleteDBClusterSnapshotOutput
 }
 
-// Mock CopyDBSnapshot
-func (m *mockRDSClient) CopyDBSnapshot(i *rds.CopyDBSnapshotInput) (*rds.CopyDBSnapshotOutput, error) {
+// Mock CopyDBSnapshotWithContext
+func (m *mockRDSClient) CopyDBSnapshotWithContext(ctx aws.Context, i *rds.CopyDBSnapshotInput, opts ...request.Option) (*rds.CopyDBSnapshotOutput, error) {
 	return m.copyDBSnapshotOutput, nil
 }
 
-// Mock DescribeDBSnapshots
-func (m *mockRDSClient) DescribeDBSnapshots(i *rds.DescribeDBSnapshotsInput) (*rds.DescribeDBSnapshotsOutput, error) {
+// Mock DescribeDBSnapshotsWithContext
+func (m *mockRDSClient) DescribeDBSnapshotsWithContext(ctx aws.Context, i *rds.DescribeDBSnapshotsInput, opts ...request.Option) (*rds.DescribeDBSnapshotsOutput, error) {
 	return m.describeDBSnapShotOutput, nil
 }
 
-// Mock DeleteDBSnapshot
-func (m *mockRDSClient) DeleteDBSnapshot(i *rds.DeleteDBSnapshotInput) (*rds.DeleteDBSnapshotOutput, error) {
+// Mock DeleteDBSnapshotWithContext
+func (m *mockRDSClient) DeleteDBSnapshotWithContext(ctx aws.Context, i *rds.DeleteDBSnapshotInput, opts ...request.Option) (*rds.DeleteDBSnapshotOutput, error) {
 	return m.deleteDBSnapshotOutput, nil
 }
 
-// Mock DescribeDBSnapshotPages
-func (m *mockRDSClient) DescribeDBSnapshotsPages(i *rds.DescribeDBSnapshotsInput, fn func(*rds.DescribeDBSnapshotsOutput, bool) bool) error {
-	fn(m.describeDBSnapShotOutput, true)
+// Mock DescribeDBSnapshotsPagesWithContext
+func (m *mockRDSClient) DescribeDBSnapshotsPagesWithContext(ctx aws.Context, i *rds.DescribeDBSnapshotsInput, fn func(*rds.DescribeDBSnapshotsOutput, bool) bool, opts ...request.Option) error {
+	if m.describeDBSnapShotPages == nil {
+		fn(m.describeDBSnapShotOutput, true)
+		return nil
+	}
+	for n, p := range m.describeDBSnapShotPages {
+		if !fn(p, n == len(m.describeDBSnapShotPages)-1) {
+			break
+		}
+	}
 	return nil
 }
+
+// Mock DescribeDBClusterSnapshotsWithContext, returning the next page each call
+func (m *mockRDSClient) DescribeDBClusterSnapshotsWithContext(ctx aws.Context, i *rds.DescribeDBClusterSnapshotsInput, opts ...request.Option) (*rds.DescribeDBClusterSnapshotsOutput, error) {
+	if len(m.describeDBClusterSnapshotsOutput) == 0 {
+		return &rds.DescribeDBClusterSnapshotsOutput{}, nil
+	}
+	r := m.describeDBClusterSnapshotsOutput[0]
+	m.describeDBClusterSnapshotsOutput = m.describeDBClusterSnapshotsOutput[1:]
+	return r, nil
+}
+
+// Mock CopyDBClusterSnapshotWithContext
+func (m *mockRDSClient) CopyDBClusterSnapshotWithContext(ctx aws.Context, i *rds.CopyDBClusterSnapshotInput, opts ...request.Option) (*rds.CopyDBClusterSnapshotOutput, error) {
+	return m.copyDBClusterSnapshotOutput, nil
+}
+
+// Mock DeleteDBClusterSnapshotWithContext
+func (m *mockRDSClient) DeleteDBClusterSnapshotWithContext(ctx aws.Context, i *rds.DeleteDBClusterSnapshotInput, opts ...request.Option) (*rds.DeleteDBClusterSnapshotOutput, error) {
+	return m.deleteDBClusterSnapshotOutput, nil
+}
+
+// Mock ModifyDBSnapshotAttributeWithContext
+func (m *mockRDSClient) ModifyDBSnapshotAttributeWithContext(ctx aws.Context, i *rds.ModifyDBSnapshotAttributeInput, opts ...request.Option) (*rds.ModifyDBSnapshotAttributeOutput, error) {
+	m.modifyDBSnapshotAttributeInput = i
+	return &rds.ModifyDBSnapshotAttributeOutput{}, nil
+}
@@ -0,0 +1,173 @@
+// Package throttle rate limits AWS API calls, and retries those that are throttled or fail transiently.
+package throttle
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// Bucket is a token bucket, allowing rate calls per second on average and bursts of up to burst calls
+type Bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewBucket returns a full bucket
+func NewBucket(rate float64, burst int) *Bucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Bucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// reserve takes a token, returning how long to wait until it is available
+func (b *Bucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.rate <= 0 {
+		return 0
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// set changes the rate and burst, keeping the tokens already in the bucket
+func (b *Bucket) set(rate float64, burst int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if burst < 1 {
+		burst = 1
+	}
+	b.rate, b.burst = rate, float64(burst)
+}
+
+// Wait blocks until a token is available, or ctx is done
+func (b *Bucket) Wait(ctx context.Context) error {
+	d := b.reserve(time.Now())
+	if d == 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Limiter holds a Bucket per region and API operation, shared by every client it is added to
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*Bucket
+}
+
+// NewLimiter returns a Limiter with no buckets
+func NewLimiter() *Limiter {
+	return &Limiter{buckets: make(map[string]*Bucket)}
+}
+
+// Bucket returns the bucket for an API operation in a region, at rate calls per second
+func (l *Limiter) Bucket(region, operation string, rate float64, burst int) *Bucket {
+	key := region + "/" + operation
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = NewBucket(rate, burst)
+		l.buckets[key] = b
+		return b
+	}
+	b.set(rate, burst)
+	return b
+}
+
+// Handler is an AWS SDK request handler which waits for a token before each attempt at a request.
+// Add it to the front of a client's Send handlers.
+func (l *Limiter) Handler(rate float64, burst int) request.NamedHandler {
+	return request.NamedHandler{
+		Name: "throttle.Limiter",
+		Fn: func(r *request.Request) {
+			if rate <= 0 || r.Operation == nil {
+				return
+			}
+			region := ""
+			if r.Config.Region != nil {
+				region = *r.Config.Region
+			}
+			if err := l.Bucket(region, r.Operation.Name, rate, burst).Wait(r.Context()); err != nil {
+				r.Error = awserr.New(request.CanceledErrorCode, "rate limit wait canceled", err)
+			}
+		},
+	}
+}
+
+// transient are the error codes, beyond those the AWS SDK already retries, that are worth retrying
+var transient = map[string]bool{
+	"InternalFailure":    true,
+	"InternalError":      true,
+	"ServiceUnavailable": true,
+	"Unavailable":        true,
+}
+
+// Retryer retries throttled and transiently failing requests up to Retries times, backing off
+// exponentially from Base to at most Max, with jitter
+type Retryer struct {
+	client.DefaultRetryer
+	Base time.Duration
+	Max  time.Duration
+}
+
+// NewRetryer returns a Retryer
+func NewRetryer(retries int, base, max time.Duration) Retryer {
+	return Retryer{DefaultRetryer: client.DefaultRetryer{NumMaxRetries: retries}, Base: base, Max: max}
+}
+
+// ShouldRetry returns true if the request should be retried
+func (d Retryer) ShouldRetry(r *request.Request) bool {
+	if aerr, ok := r.Error.(awserr.Error); ok && transient[aerr.Code()] {
+		return true
+	}
+	return d.DefaultRetryer.ShouldRetry(r)
+}
+
+// RetryRules returns the delay before the next attempt at the request
+func (d Retryer) RetryRules(r *request.Request) time.Duration {
+	return Backoff(r.RetryCount, d.Base, d.Max)
+}
+
+// Backoff returns a random delay between d/2 and d, where d is base doubled for each retry, capped at max.
+// A max of 0 is no cap.
+func Backoff(retry int, base, max time.Duration) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	d := base
+	for i := 0; i < retry && (max <= 0 || d < max) && d < math.MaxInt64/2; i++ {
+		d *= 2
+	}
+	if max > 0 && d > max {
+		d = max
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
@@ -0,0 +1,126 @@
+package throttle
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+func TestBucket(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	b := NewBucket(2, 2)
+	b.last = now
+
+	// The burst is free, then calls are spaced at the rate
+	for i, want := range []time.Duration{0, 0, 500 * time.Millisecond, time.Second} {
+		if got := b.reserve(now); got != want {
+			t.Errorf("reserve() %d = %v, want %v", i, got, want)
+		}
+	}
+	// The bucket refills over time
+	if got := b.reserve(now.Add(3 * time.Second)); got != 0 {
+		t.Errorf("reserve() after refill = %v, want 0", got)
+	}
+
+	unlimited := NewBucket(0, 0)
+	for i := 0; i < 100; i++ {
+		if got := unlimited.reserve(now); got != 0 {
+			t.Fatalf("reserve() unlimited = %v, want 0", got)
+		}
+	}
+}
+
+func TestBucketWait(t *testing.T) {
+	t.Parallel()
+
+	b := NewBucket(0.001, 1)
+	if err := b.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := b.Wait(ctx); err != context.Canceled {
+		t.Errorf("Wait() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	t.Parallel()
+
+	l := NewLimiter()
+	a := l.Bucket("us-west-2", "CopyDBSnapshot", 1, 1)
+	if l.Bucket("us-west-2", "CopyDBSnapshot", 2, 1) != a {
+		t.Errorf("Bucket() did not share the bucket of an API in a region")
+	}
+	if a.rate != 2 {
+		t.Errorf("Bucket() rate = %v, want 2", a.rate)
+	}
+	if l.Bucket("eu-west-1", "CopyDBSnapshot", 1, 1) == a || l.Bucket("us-west-2", "DescribeDBSnapshots", 1, 1) == a {
+		t.Errorf("Bucket() shared a bucket between regions or APIs")
+	}
+}
+
+func TestRetryerShouldRetry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		status int
+		err    error
+		want   bool
+	}{
+		{"ShouldRetry_throttling", 400, awserr.New("Throttling", "Rate exceeded", nil), true},
+		{"ShouldRetry_internal_failure", 400, awserr.New("InternalFailure", "", nil), true},
+		{"ShouldRetry_unavailable", 503, awserr.New("Unknown", "", nil), true},
+		{"ShouldRetry_not_found", 404, awserr.New("DBSnapshotNotFound", "", nil), false},
+		{"ShouldRetry_quota", 400, awserr.New("SnapshotQuotaExceeded", "", nil), false},
+	}
+
+	r := NewRetryer(3, time.Second, time.Minute)
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			req := &request.Request{HTTPResponse: &http.Response{StatusCode: tt.status}, Error: tt.err}
+			if got := r.ShouldRetry(req); got != tt.want {
+				t.Errorf("%v = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		retry    int
+		min, max time.Duration
+	}{
+		{0, 500 * time.Millisecond, time.Second},
+		{1, time.Second, 2 * time.Second},
+		{3, 4 * time.Second, 8 * time.Second},
+		{10, 5 * time.Second, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if got := Backoff(tt.retry, time.Second, 10*time.Second); got < tt.min || got > tt.max {
+				t.Errorf("Backoff(%d) = %v, want %v to %v", tt.retry, got, tt.min, tt.max)
+			}
+		}
+	}
+	for i := 0; i < 20; i++ {
+		if got := Backoff(6, time.Second, 0); got < 32*time.Second || got > 64*time.Second {
+			t.Errorf("Backoff(6) with no max = %v, want %v to %v", got, 32*time.Second, 64*time.Second)
+		}
+	}
+	if got := Backoff(5, 0, time.Second); got != 0 {
+		t.Errorf("Backoff() with no base = %v, want 0", got)
+	}
+}
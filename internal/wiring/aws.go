@@ -1,22 +1,121 @@
 package wiring
 
 import (
+	"context"
 	"fmt"
+	"net/url"
+	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sts"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/metrics"
+	"github.com/bluebenno/rds-snapshot-copier/internal/throttle"
 )
 
-// Session initialises a connection for an AWS rds, to a particular region
-func Session(cfg *Config, region string) (*rds.RDS, error) {
-	s := session.Must(session.NewSession(&aws.Config{
-		Region: aws.String(region),
-	}))
+// limiter is shared by every Session, so calls to an AWS API in a region are limited across all of them
+var limiter = throttle.NewLimiter()
+
+// Session initialises a connection for an AWS rds, to a particular region.
+// If role is set, the role is assumed (with cfg.ExternalID), otherwise the ambient credentials, or cfg's static
+// ones, are used. The region's endpoint may be overridden, see Config.Endpoint.
+// Calls are rate limited to cfg.APIRate, and retried with backoff when throttled or failing transiently.
+func Session(cfg *Config, region, role string) (*rds.RDS, error) {
+	s := base(cfg, region)
+
+	// Only rds uses the endpoint; roles are still assumed with AWS STS
+	rc := &aws.Config{}
+	if e := cfg.Endpoint(region); e != "" {
+		rc.Endpoint = aws.String(e)
+	}
+	if role != "" {
+		rc.Credentials = stscreds.NewCredentials(s, role, func(p *stscreds.AssumeRoleProvider) {
+			if cfg.ExternalID != "" {
+				p.ExternalID = aws.String(cfg.ExternalID)
+			}
+		})
+	}
+	rs := rds.New(s, rc)
 
-	rs := rds.New(s)
 	if rs != nil {
+		rs.Handlers.Send.PushFrontNamed(limiter.Handler(cfg.APIRate, cfg.APIBurst))
+		rs.Handlers.Complete.PushBackNamed(metrics.AWSErrors)
 		return rs, nil
 	}
 	return nil, fmt.Errorf("failed to initate a Session to the AWS rds endpoint")
 }
+
+// base returns a session in region with the ambient, or cfg's static, credentials, retrying as cfg says
+func base(cfg *Config, region string) *session.Session {
+	ac := &aws.Config{
+		Region: aws.String(region),
+	}
+	if cfg.StaticKeyID != "" {
+		ac.Credentials = credentials.NewStaticCredentials(cfg.StaticKeyID, cfg.StaticSecret, "")
+	}
+	return session.Must(session.NewSession(request.WithRetryer(ac, throttle.NewRetryer(cfg.APIRetries, cfg.APIRetryBase, cfg.APIRetryMax))))
+}
+
+// accounts caches the account ID of the credentials, by static access key ID or "" for the ambient ones
+var accounts sync.Map
+
+// CallerAccount returns the AWS account ID of the ambient, or cfg's static, credentials, asking STS once
+func CallerAccount(ctx context.Context, cfg *Config) (string, error) {
+	if a, ok := accounts.Load(cfg.StaticKeyID); ok {
+		return a.(string), nil
+	}
+	out, err := sts.New(base(cfg, cfg.SourceRegion)).GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	a := aws.StringValue(out.Account)
+	accounts.Store(cfg.StaticKeyID, a)
+	return a, nil
+}
+
+// Queue initialises a connection to the SQS queue at queueURL, e.g.
+// https://sqs.ap-southeast-2.amazonaws.com/123456789012/snapshot-events. The region is that of the queue, or
+// cfg.SourceRegion if the URL does not say. A queue not hosted by AWS, e.g. a local stand-in such as
+// http://localhost:9324/queue/events, is connected to directly.
+func Queue(cfg *Config, queueURL string) (*sqs.SQS, error) {
+	u, err := url.Parse(queueURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid queue URL %q", queueURL)
+	}
+
+	ac := &aws.Config{Region: aws.String(cfg.SourceRegion)}
+	if strings.HasSuffix(u.Hostname(), ".amazonaws.com") {
+		for _, h := range strings.Split(u.Hostname(), ".") {
+			if IsRegion(h) {
+				ac.Region = aws.String(h)
+				break
+			}
+		}
+	} else {
+		ac.Endpoint = aws.String(u.Scheme + "://" + u.Host)
+	}
+
+	s, err := session.NewSession(ac)
+	if err != nil {
+		return nil, err
+	}
+	q := sqs.New(s)
+	q.Handlers.Complete.PushBackNamed(metrics.AWSErrors)
+	return q, nil
+}
+
+// Account returns the AWS account ID of an IAM role ARN, e.g. arn:aws:iam::123456789012:role/copier
+func Account(roleARN string) string {
+	f := strings.Split(roleARN, ":")
+	if len(f) < 6 {
+		return ""
+	}
+	return f[4]
+}
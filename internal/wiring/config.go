@@ -1,14 +1,126 @@
 package wiring
 
+import (
+	"strings"
+	"time"
+)
+
 // Config defines the app config
 type Config struct {
+	APIBurst        int           // Calls to each AWS API, per region, may burst to APIBurst
+	APIRate         float64       // Calls per second to each AWS API, per region, 0 is unlimited
+	APIRetries      int           // Throttled and transiently failing AWS API calls are retried APIRetries times
+	APIRetryBase    time.Duration // The first retry backs off by about APIRetryBase, doubling up to APIRetryMax
+	APIRetryMax     time.Duration
+	ConfigFile      string        // Optional YAML, JSON or TOML file of defaults and Overrides
+	CopyTimeout     time.Duration // A copy still in progress after CopyTimeout is aborted, 0 waits forever
 	DryRun          bool
-	Tag             string // An AWS Tag on the rds, which will flag copying of the snapshots
+	Endpoints       map[string]string // RDS endpoint URL per region, e.g. a local stand-in; "*" applies to every region
+	EventQueue      string            // If set, copy snapshots as soon as the RDS events for them arrive on this SQS queue URL
+	EventWebhook    bool              // Copy snapshots as soon as the RDS events for them are POSTed to /events on ListenAddr
+	ExternalID      string            // The external ID used when assuming SourceRoleARN or TargetRoleARN
+	Tag             string            // An AWS Tag on the rds, which will flag copying of the snapshots
+	KeepDaily       int               // GFS retention; keep the latest snapshot of each of the last KeepDaily days
+	KeepMonthly     int               // GFS retention; keep the latest snapshot of each of the last KeepMonthly months
+	KeepWeekly      int               // GFS retention; keep the latest snapshot of each of the last KeepWeekly weeks
+	ListenAddr      string            // If set, serve HTTP endpoints, e.g. Prometheus metrics, on this address
+	LiveCycles      int               // The health check fails if no cycle completes within LiveCycles × RunEvery, 0 never fails
 	LogLevel        string
+	Lookback        time.Duration // If set, copy every snapshot taken within Lookback missing from the target, not just the latest
+	MaxAgeDays      int           // Keep snapshots younger than MaxAgeDays days
 	MaxCopyInFlight int
-	MaxSnap         int
+	MaxSnap         int           // Keep the latest MaxSnap snapshots
+	NoWait          bool          // Start copies, and check on those in progress, without waiting for them to finish
+	Once            bool          // Run a single cycle and exit, rather than every RunEvery minutes
+	Overrides       []Override    // From ConfigFile; the copy policy of the rds instances and clusters they match
+	RPO             time.Duration // The check fails if the newest copy of an rds is older than RPO
+	Report          string        // Write each run's report as JSON to this file, or "-" for stdout
 	RunEvery        int
+	Schedule        time.Duration // Minimum time between copies of an rds's snapshots, 0 copies every new snapshot
+	SnapshotType    string        // "instance", "cluster" or "all"; which kinds of snapshot are copied
+	SourceAccount   string        // Not configured; the account ID of the ambient credentials, see CallerAccount
 	SourceRegion    string
-	TargetKMS       string
-	TargetRegion    string
+	SourceRoleARN   string // Optional IAM role to assume in the source account
+	StaticKeyID     string // Static AWS credentials, e.g. for a local stand-in, rather than the ambient ones
+	StaticSecret    string
+	TargetKMS       string            // Default KMS key for the target region(s)
+	TargetKMSRegion map[string]string // KMS key per target region, overrides TargetKMS
+	TargetRegion    string            // Comma separated; the default target region(s)
+	TargetRoleARN   string            // Optional IAM role to assume in the target account
+}
+
+// Override is the copy policy, from the config file, of the rds instances and Aurora clusters whose identifiers
+// match a pattern
+type Override struct {
+	Match   string            // An identifier, or a pattern as for path.Match, e.g. "prod-*"
+	Enabled *bool             // If set, whether they are copied, whatever their tag
+	Policy  map[string]string // Copy policy keys and values, as in the tag, e.g. "keep": "7"; see policy.Parse
+}
+
+// Instances reports if rds instance snapshots are in scope
+func (c *Config) Instances() bool {
+	return c.SnapshotType == "" || c.SnapshotType == "instance" || c.SnapshotType == "all"
+}
+
+// Clusters reports if Aurora cluster snapshots are in scope
+func (c *Config) Clusters() bool {
+	return c.SnapshotType == "cluster" || c.SnapshotType == "all"
+}
+
+// Retention reports if any retention rule is set, and so target snapshots are housekept
+func (c *Config) Retention() bool {
+	return c.MaxSnap > 0 || c.AgeOrGFS()
+}
+
+// AgeOrGFS reports if the age or GFS (daily, weekly, monthly) retention rules are set
+func (c *Config) AgeOrGFS() bool {
+	return c.MaxAgeDays > 0 || c.KeepDaily > 0 || c.KeepWeekly > 0 || c.KeepMonthly > 0
+}
+
+// Endpoint returns the RDS endpoint URL for a region, or "" for that of AWS
+func (c *Config) Endpoint(region string) string {
+	if e, ok := c.Endpoints[region]; ok {
+		return e
+	}
+	return c.Endpoints["*"]
+}
+
+// CrossAccount returns the target account ID if the target is a different AWS account to the source, otherwise "".
+// Snapshots must be shared with that account before they can be copied. The source account is that of
+// SourceRoleARN or, without one, SourceAccount.
+func (c *Config) CrossAccount() string {
+	if c.TargetRoleARN == "" {
+		return ""
+	}
+	t := Account(c.TargetRoleARN)
+	s := c.SourceAccount
+	if c.SourceRoleARN != "" {
+		s = Account(c.SourceRoleARN)
+	}
+	if s == t {
+		return ""
+	}
+	return t
+}
+
+// TargetRegions returns the default target region(s)
+func (c *Config) TargetRegions() []string {
+	return SplitList(c.TargetRegion)
+}
+
+// ForRegion returns a copy of the config for copying to a single target region, with that regions KMS key
+func (c *Config) ForRegion(region string) *Config {
+	rc := *c
+	rc.TargetRegion = region
+	if k, ok := c.TargetKMSRegion[region]; ok {
+		rc.TargetKMS = k
+	}
+	return &rc
+}
+
+// SplitList splits a list separated by commas, plus signs or whitespace. Empty items are dropped
+func SplitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '+' || r == ' ' || r == '\t' || r == '\n'
+	})
 }
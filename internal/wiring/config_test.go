@@ -0,0 +1,45 @@
+package wiring
+
+import "testing"
+
+func TestCrossAccount(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{name: "CrossAccount_no_target_role", cfg: Config{SourceAccount: "123456789012"}},
+		{
+			name: "CrossAccount_target_role_same_account",
+			cfg:  Config{SourceAccount: "123456789012", TargetRoleARN: "arn:aws:iam::123456789012:role/copier"},
+		},
+		{
+			name: "CrossAccount_target_role_other_account",
+			cfg:  Config{SourceAccount: "123456789012", TargetRoleARN: "arn:aws:iam::210987654321:role/copier"},
+			want: "210987654321",
+		},
+		{
+			name: "CrossAccount_source_role_same_account",
+			cfg: Config{SourceAccount: "555555555555", SourceRoleARN: "arn:aws:iam::210987654321:role/reader",
+				TargetRoleARN: "arn:aws:iam::210987654321:role/copier"},
+		},
+		{
+			name: "CrossAccount_source_role_other_account",
+			cfg: Config{SourceRoleARN: "arn:aws:iam::123456789012:role/reader",
+				TargetRoleARN: "arn:aws:iam::210987654321:role/copier"},
+			want: "210987654321",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.cfg.CrossAccount(); got != tt.want {
+				t.Errorf("CrossAccount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
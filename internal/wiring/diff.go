@@ -0,0 +1,87 @@
+package wiring
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Change is a setting which differs between two configs
+type Change struct {
+	Field string // The Config field, e.g. "Tag"
+	Old   string
+	New   string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Field, c.Old, c.New)
+}
+
+// secret are the fields whose values are never shown
+var secret = map[string]bool{"StaticSecret": true}
+
+// Diff returns the settings which differ from c in o, in field order
+func (c *Config) Diff(o *Config) []Change {
+	var changes []Change
+	cv, ov := reflect.ValueOf(*c), reflect.ValueOf(*o)
+	for i := 0; i < cv.NumField(); i++ {
+		f := cv.Type().Field(i).Name
+		a, b := cv.Field(i).Interface(), ov.Field(i).Interface()
+		if reflect.DeepEqual(a, b) || (empty(a) && empty(b)) {
+			continue
+		}
+		if secret[f] {
+			changes = append(changes, Change{Field: f, Old: "(hidden)", New: "(hidden)"})
+			continue
+		}
+		changes = append(changes, Change{Field: f, Old: show(a), New: show(b)})
+	}
+	return changes
+}
+
+// empty reports if v is a nil or empty map or slice, which are equivalent settings
+func empty(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map, reflect.Slice:
+		return rv.Len() == 0
+	}
+	return false
+}
+
+// show formats a setting, with maps in key order and overrides as their pattern and policy
+func show(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case map[string]string:
+		return showMap(v)
+	case []Override:
+		s := make([]string, len(v))
+		for i, o := range v {
+			s[i] = o.Match
+			if o.Enabled != nil {
+				s[i] += fmt.Sprintf(" enabled=%t", *o.Enabled)
+			}
+			if len(o.Policy) > 0 {
+				s[i] += " " + showMap(o.Policy)
+			}
+		}
+		return "[" + strings.Join(s, ", ") + "]"
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func showMap(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	s := make([]string, len(keys))
+	for i, k := range keys {
+		s[i] = k + "=" + m[k]
+	}
+	return "{" + strings.Join(s, " ") + "}"
+}
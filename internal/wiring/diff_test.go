@@ -0,0 +1,69 @@
+package wiring
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	yes := true
+	base := func() *Config {
+		return &Config{SourceRegion: "ap-southeast-2", TargetRegion: "us-west-2", Tag: "COPYTO", MaxSnap: 7,
+			CopyTimeout: 24 * time.Hour, StaticSecret: "s3cret"}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   []Change
+	}{
+		{name: "Diff_unchanged", modify: func(c *Config) {}},
+		{
+			name:   "Diff_nil_and_empty_maps",
+			modify: func(c *Config) { c.Endpoints, c.TargetKMSRegion = map[string]string{}, map[string]string{} },
+		},
+		{
+			name: "Diff_fields",
+			modify: func(c *Config) {
+				c.Tag, c.MaxSnap, c.CopyTimeout, c.DryRun = "backup", 14, 12*time.Hour, true
+			},
+			want: []Change{
+				{Field: "CopyTimeout", Old: "24h0m0s", New: "12h0m0s"},
+				{Field: "DryRun", Old: "false", New: "true"},
+				{Field: "Tag", Old: `"COPYTO"`, New: `"backup"`},
+				{Field: "MaxSnap", Old: "7", New: "14"},
+			},
+		},
+		{
+			name:   "Diff_secret_hidden",
+			modify: func(c *Config) { c.StaticSecret = "other" },
+			want:   []Change{{Field: "StaticSecret", Old: "(hidden)", New: "(hidden)"}},
+		},
+		{
+			name: "Diff_maps_and_overrides",
+			modify: func(c *Config) {
+				c.TargetKMSRegion = map[string]string{"us-west-2": "k2", "eu-west-1": "k1"}
+				c.Overrides = []Override{{Match: "prod-*", Enabled: &yes, Policy: map[string]string{"keep": "7"}}}
+			},
+			want: []Change{
+				{Field: "Overrides", Old: "[]", New: "[prod-* enabled=true {keep=7}]"},
+				{Field: "TargetKMSRegion", Old: "{}", New: "{eu-west-1=k1 us-west-2=k2}"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := base()
+			tt.modify(c)
+			if got := base().Diff(c); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
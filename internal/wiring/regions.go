@@ -0,0 +1,15 @@
+package wiring
+
+import (
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+)
+
+// IsRegion reports if name is a region known to the AWS SDK, in any partition
+func IsRegion(name string) bool {
+	for _, p := range endpoints.DefaultPartitions() {
+		if _, ok := p.Regions()[name]; ok {
+			return true
+		}
+	}
+	return false
+}
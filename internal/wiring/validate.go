@@ -0,0 +1,175 @@
+package wiring
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/arn"
+)
+
+// Problems is every problem found with a Config
+type Problems []string
+
+func (p Problems) Error() string {
+	if len(p) == 1 {
+		return p[0]
+	}
+	return fmt.Sprintf("%d problems:\n  %s", len(p), strings.Join(p, "\n  "))
+}
+
+// Validate checks the config, before anything is copied, returning Problems listing all that is wrong with it, or nil.
+// Settings are named by their environmental variables.
+func (c *Config) Validate() error {
+	var p Problems
+	add := func(format string, a ...interface{}) {
+		p = append(p, fmt.Sprintf(format, a...))
+	}
+
+	switch {
+	case c.SourceRegion == "":
+		add("SOURCE_REGION is required")
+	case !IsRegion(c.SourceRegion):
+		add("SOURCE_REGION %q is not an AWS region", c.SourceRegion)
+	}
+
+	targets := c.TargetRegions()
+	if len(targets) == 0 {
+		add("TARGET_REGION is required")
+	}
+	for _, t := range targets {
+		switch {
+		case !IsRegion(t):
+			add("TARGET_REGION %q is not an AWS region", t)
+		case t == c.SourceRegion:
+			add("TARGET_REGION %q is the source region; snapshots must be copied to another region", t)
+		}
+	}
+
+	if c.Tag == "" && !c.enables() {
+		add("TAG is required, unless a config file override enables rds")
+	}
+	if c.MaxCopyInFlight < 1 {
+		add("MAX_SNAPSHOT_FLIGHT must be at least 1, got %d", c.MaxCopyInFlight)
+	}
+
+	// A key is only used in the region it is in
+	if c.TargetKMS != "" {
+		for _, t := range targets {
+			if _, ok := c.TargetKMSRegion[t]; ok || !IsRegion(t) {
+				continue
+			}
+			if err := kmsKey(c.TargetKMS, t); err == errNotKMS {
+				add("TARGET_KMS %q is not a KMS key ARN, e.g. arn:aws:kms:%s:123456789012:key/...", c.TargetKMS, t)
+				break
+			} else if err != nil {
+				add("TARGET_KMS %v; set TARGET_KMS_REGION for %s", err, t)
+			}
+		}
+	}
+	for r, k := range c.TargetKMSRegion {
+		if !IsRegion(r) {
+			add("TARGET_KMS_REGION %q is not an AWS region", r)
+			continue
+		}
+		if err := kmsKey(k, r); err == errNotKMS {
+			add("TARGET_KMS_REGION for %s %q is not a KMS key ARN, e.g. arn:aws:kms:%s:123456789012:key/...", r, k, r)
+		} else if err != nil {
+			add("TARGET_KMS_REGION for %s %v", r, err)
+		}
+	}
+
+	for name, role := range map[string]string{"SOURCE_ROLE_ARN": c.SourceRoleARN, "TARGET_ROLE_ARN": c.TargetRoleARN} {
+		if role == "" {
+			continue
+		}
+		a, err := arn.Parse(role)
+		if err != nil || a.Service != "iam" || !strings.HasPrefix(a.Resource, "role/") || a.AccountID == "" {
+			add("%s %q is not an IAM role ARN, e.g. arn:aws:iam::123456789012:role/copier", name, role)
+		}
+	}
+
+	for r, e := range c.Endpoints {
+		if r != "*" && !IsRegion(r) {
+			add("RDS_ENDPOINT %q is not an AWS region, or *", r)
+		}
+		if u, err := url.Parse(e); err != nil || u.Scheme == "" || u.Host == "" {
+			add("RDS_ENDPOINT for %s %q is not a URL, e.g. http://localhost:4597", r, e)
+		}
+	}
+	if (c.StaticKeyID == "") != (c.StaticSecret == "") {
+		add("STATIC_ACCESS_KEY_ID and STATIC_SECRET_ACCESS_KEY must be set together")
+	}
+
+	if c.EventWebhook && c.ListenAddr == "" {
+		add("EVENT_WEBHOOK requires LISTEN_ADDR")
+	}
+
+	for name, n := range map[string]int{
+		"API_RETRIES": c.APIRetries, "KEEP_DAILY": c.KeepDaily, "KEEP_MONTHLY": c.KeepMonthly, "KEEP_WEEKLY": c.KeepWeekly,
+		"LIVENESS_CYCLES": c.LiveCycles, "MAX_AGE_DAYS": c.MaxAgeDays, "MAX_SNAPSHOT_TARGET": c.MaxSnap, "RUN_EVERY_MINS": c.RunEvery,
+	} {
+		if n < 0 {
+			add("%s must not be negative, got %d", name, n)
+		}
+	}
+	for name, d := range map[string]time.Duration{
+		"API_RETRY_BASE": c.APIRetryBase, "API_RETRY_MAX": c.APIRetryMax, "COPY_TIMEOUT": c.CopyTimeout,
+		"COPY_SCHEDULE": c.Schedule, "LOOKBACK": c.Lookback, "RPO": c.RPO,
+	} {
+		if d < 0 {
+			add("%s must not be negative, got %v", name, d)
+		}
+	}
+	if c.APIRate < 0 {
+		add("API_RATE must not be negative, got %v", c.APIRate)
+	}
+	if c.APIRate > 0 && c.APIBurst < 1 {
+		add("API_BURST must be at least 1 when API_RATE is set, got %d", c.APIBurst)
+	}
+	if c.APIRetryMax > 0 && c.APIRetryMax < c.APIRetryBase {
+		add("API_RETRY_MAX (%v) must not be less than API_RETRY_BASE (%v)", c.APIRetryMax, c.APIRetryBase)
+	}
+
+	for _, o := range c.Overrides {
+		if _, err := path.Match(o.Match, ""); err != nil {
+			add("override %q is not a valid pattern: %v", o.Match, err)
+		}
+	}
+
+	if len(p) == 0 {
+		return nil
+	}
+	// Map iteration order is random; keep the report stable
+	sort.Strings(p)
+	return p
+}
+
+// enables reports if any config file override enables the rds it matches
+func (c *Config) enables() bool {
+	for _, o := range c.Overrides {
+		if o.Enabled != nil && *o.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// errNotKMS is returned by kmsKey for a key which is not a KMS ARN at all
+var errNotKMS = errors.New("not a KMS key ARN")
+
+// kmsKey checks that key is the ARN of a KMS key, or alias, in region
+func kmsKey(key, region string) error {
+	a, err := arn.Parse(key)
+	if err != nil || a.Service != "kms" || !(strings.HasPrefix(a.Resource, "key/") || strings.HasPrefix(a.Resource, "alias/")) {
+		return errNotKMS
+	}
+	if a.Region != region {
+		return fmt.Errorf("%q is in %s, not the target region %s", key, a.Region, region)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package wiring
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := func() *Config {
+		return &Config{SourceRegion: "ap-southeast-2", TargetRegion: "us-west-2", Tag: "COPYTO", MaxCopyInFlight: 2,
+			APIRate: 5, APIBurst: 10, APIRetries: 8, APIRetryBase: time.Second, APIRetryMax: time.Minute}
+	}
+	yes := true
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   Problems
+	}{
+		{name: "Validate_valid", modify: func(c *Config) {}},
+		{
+			name: "Validate_empty",
+			modify: func(c *Config) {
+				*c = Config{}
+			},
+			want: Problems{
+				"MAX_SNAPSHOT_FLIGHT must be at least 1, got 0",
+				"SOURCE_REGION is required",
+				"TAG is required, unless a config file override enables rds",
+				"TARGET_REGION is required",
+			},
+		},
+		{
+			name: "Validate_regions",
+			modify: func(c *Config) {
+				c.TargetRegion = "us-west-2,ap-southeast-2,mars-north-1"
+			},
+			want: Problems{
+				`TARGET_REGION "ap-southeast-2" is the source region; snapshots must be copied to another region`,
+				`TARGET_REGION "mars-north-1" is not an AWS region`,
+			},
+		},
+		{
+			name:   "Validate_unknown_source",
+			modify: func(c *Config) { c.SourceRegion = "mars-north-1" },
+			want:   Problems{`SOURCE_REGION "mars-north-1" is not an AWS region`},
+		},
+		{
+			name:   "Validate_override_enables_without_tag",
+			modify: func(c *Config) { c.Tag, c.Overrides = "", []Override{{Match: "prod-*", Enabled: &yes}} },
+		},
+		{
+			name: "Validate_kms_in_target_region",
+			modify: func(c *Config) {
+				c.TargetRegion = "us-west-2,eu-west-1"
+				c.TargetKMS = "arn:aws:kms:us-west-2:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+				c.TargetKMSRegion = map[string]string{"eu-west-1": "arn:aws:kms:eu-west-1:123456789012:alias/dr"}
+			},
+		},
+		{
+			name: "Validate_kms_problems",
+			modify: func(c *Config) {
+				c.TargetRegion = "us-west-2,eu-west-1"
+				c.TargetKMS = "arn:aws:kms:us-west-2:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+				c.TargetKMSRegion = map[string]string{"us-west-2": "alias/dr", "mars-north-1": "arn:aws:kms:mars-north-1:123456789012:alias/dr"}
+			},
+			want: Problems{
+				`TARGET_KMS "arn:aws:kms:us-west-2:123456789012:key/1234abcd-12ab-34cd-56ef-1234567890ab" is in us-west-2, not the target region eu-west-1; set TARGET_KMS_REGION for eu-west-1`,
+				`TARGET_KMS_REGION "mars-north-1" is not an AWS region`,
+				`TARGET_KMS_REGION for us-west-2 "alias/dr" is not a KMS key ARN, e.g. arn:aws:kms:us-west-2:123456789012:key/...`,
+			},
+		},
+		{
+			name:   "Validate_retry_max_uncapped",
+			modify: func(c *Config) { c.APIRetryMax = 0 },
+		},
+		{
+			name: "Validate_kms_not_arn",
+			modify: func(c *Config) {
+				c.TargetRegion = "us-west-2,eu-west-1"
+				c.TargetKMS = "alias/dr"
+			},
+			want: Problems{`TARGET_KMS "alias/dr" is not a KMS key ARN, e.g. arn:aws:kms:us-west-2:123456789012:key/...`},
+		},
+		{
+			name: "Validate_access",
+			modify: func(c *Config) {
+				c.SourceRoleARN = "copier"
+				c.TargetRoleARN = "arn:aws:iam::123456789012:role/copier"
+				c.Endpoints = map[string]string{"*": "http://localhost:4597", "us-west-2": "localhost"}
+				c.StaticKeyID = "fake"
+				c.EventWebhook = true
+			},
+			want: Problems{
+				"EVENT_WEBHOOK requires LISTEN_ADDR",
+				`RDS_ENDPOINT for us-west-2 "localhost" is not a URL, e.g. http://localhost:4597`,
+				`SOURCE_ROLE_ARN "copier" is not an IAM role ARN, e.g. arn:aws:iam::123456789012:role/copier`,
+				"STATIC_ACCESS_KEY_ID and STATIC_SECRET_ACCESS_KEY must be set together",
+			},
+		},
+		{
+			name: "Validate_negative",
+			modify: func(c *Config) {
+				c.MaxSnap = -1
+				c.CopyTimeout = -time.Hour
+				c.APIRate, c.APIBurst = 1, 0
+				c.APIRetryMax = time.Millisecond
+			},
+			want: Problems{
+				"API_BURST must be at least 1 when API_RATE is set, got 0",
+				"API_RETRY_MAX (1ms) must not be less than API_RETRY_BASE (1s)",
+				"COPY_TIMEOUT must not be negative, got -1h0m0s",
+				"MAX_SNAPSHOT_TARGET must not be negative, got -1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := valid()
+			tt.modify(c)
+			err := c.Validate()
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			got, ok := err.(Problems)
+			if !ok {
+				t.Fatalf("Validate() = %v, want Problems", err)
+			}
+			want := append(Problems(nil), tt.want...)
+			sort.Strings(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Validate() = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"go.uber.org/zap"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/metrics"
+	"github.com/bluebenno/rds-snapshot-copier/internal/snapops"
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+// Lag is how far the copies of an rds in a target region are behind its snapshots in the source region
+type Lag struct {
+	Source       string        `json:"source"`
+	TargetRegion string        `json:"target_region"`
+	NewestSource *time.Time    `json:"newest_source,omitempty"` // When the newest available source snapshot was taken
+	NewestCopy   *time.Time    `json:"newest_copy,omitempty"`   // When the source of the newest available copy was taken
+	Lag          time.Duration `json:"lag"`                     // NewestSource - NewestCopy
+	Age          time.Duration `json:"age"`                     // How old NewestCopy is
+	RPO          time.Duration `json:"rpo"`
+	Breached     bool          `json:"breached"` // No copy, or the newest is older than RPO
+	Error        string        `json:"error,omitempty"`
+}
+
+// snap is the part of an rds instance or Aurora cluster snapshot needed to work out the lag
+type snap struct {
+	id        string
+	created   *time.Time
+	available bool
+}
+
+// Check works out the Lag of every in scope rds instance and Aurora cluster, in each of its target regions.
+// A database breaches its RPO if its newest copy is older than cfg.RPO (as overridden by its tag), or it could
+// not be checked. The metrics are updated with the results.
+func Check(ctx context.Context, logger *zap.Logger, cfg *wiring.Config) ([]Lag, error) {
+	SrcRDSSource, err := newSession(cfg, cfg.SourceRegion, cfg.SourceRoleARN)
+	if err != nil {
+		return nil, err
+	}
+	inscopeRDS, inscopeClusters, err := inScope(ctx, logger, nil, cfg, SrcRDSSource)
+	if err != nil {
+		return nil, err
+	}
+
+	var lags []Lag
+	now := time.Now()
+	for _, region := range targetRegions(inscopeRDS, inscopeClusters) {
+		rcfg := cfg.ForRegion(region)
+		SrcRDSTarget, err := newSession(rcfg, region, rcfg.TargetRoleARN)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, is := range inscopeRDS {
+			if !contains(is.Config.TargetRegions(), region) {
+				continue
+			}
+			icfg := is.Config.ForRegion(region)
+			id := *is.Instance.DBInstanceIdentifier
+
+			source, err := snapops.List(ctx, SrcRDSSource, id)
+			if err != nil {
+				lags = append(lags, failedLag(icfg, id, err))
+				continue
+			}
+			target, err := snapops.List(ctx, SrcRDSTarget, id)
+			if err != nil {
+				lags = append(lags, failedLag(icfg, id, err))
+				continue
+			}
+			lags = append(lags, lagOf(icfg, id, snaps(source), snaps(snapops.Copies(icfg, target)), now))
+		}
+
+		for _, is := range inscopeClusters {
+			if !contains(is.Config.TargetRegions(), region) {
+				continue
+			}
+			ccfg := is.Config.ForRegion(region)
+			id := *is.Cluster.DBClusterIdentifier
+
+			source, err := snapops.ListCluster(ctx, SrcRDSSource, id)
+			if err != nil {
+				lags = append(lags, failedLag(ccfg, id, err))
+				continue
+			}
+			target, err := snapops.ListCluster(ctx, SrcRDSTarget, id)
+			if err != nil {
+				lags = append(lags, failedLag(ccfg, id, err))
+				continue
+			}
+			lags = append(lags, lagOf(ccfg, id, clusterSnaps(source), clusterSnaps(snapops.CopiesCluster(ccfg, target)), now))
+		}
+	}
+
+	for _, l := range lags {
+		breached := 0.0
+		if l.Breached {
+			breached = 1
+		}
+		metrics.RPOBreached.WithLabelValues(l.Source, l.TargetRegion).Set(breached)
+		metrics.CopyAge.WithLabelValues(l.Source, l.TargetRegion).Set(l.Age.Seconds())
+	}
+	return lags, nil
+}
+
+// Breached returns the lags which breach their RPO
+func Breached(lags []Lag) []Lag {
+	var b []Lag
+	for _, l := range lags {
+		if l.Breached {
+			b = append(b, l)
+		}
+	}
+	return b
+}
+
+// rpoErr runs the RPO check, updating its metrics, and logs any rds whose RPO is breached. It returns an error
+// naming them, or nil if none are, or the check itself failed.
+func rpoErr(ctx context.Context, logger *zap.Logger, cfg *wiring.Config) error {
+	lags, err := Check(ctx, logger, cfg)
+	if err != nil {
+		logger.Warn("Failed to check RPO", zap.String("source_region", cfg.SourceRegion), zap.Error(err))
+		return nil
+	}
+	var breached []string
+	for _, l := range Breached(lags) {
+		logger.Warn("RPO breached", zap.String("rds", l.Source), zap.String("target_region", l.TargetRegion),
+			zap.Duration("age", l.Age), zap.Duration("rpo", l.RPO), zap.String("error", l.Error))
+		breached = append(breached, l.Source+" in "+l.TargetRegion)
+	}
+	if len(breached) > 0 {
+		return fmt.Errorf("RPO breached by %s", strings.Join(breached, ", "))
+	}
+	return nil
+}
+
+// failedLag is the Lag of an rds which could not be checked
+func failedLag(cfg *wiring.Config, id string, err error) Lag {
+	return Lag{Source: id, TargetRegion: cfg.TargetRegion, RPO: cfg.RPO, Breached: true, Error: err.Error()}
+}
+
+// lagOf works out the Lag of an rds, from its source snapshots and the copies of them in the target region.
+// The age of a copy is that of the source snapshot it was copied from, when that is still in the source region.
+func lagOf(cfg *wiring.Config, id string, source, copies []snap, now time.Time) Lag {
+	l := Lag{Source: id, TargetRegion: cfg.TargetRegion, RPO: cfg.RPO}
+
+	taken := make(map[string]*time.Time)
+	for _, s := range source {
+		if !s.available || s.created == nil {
+			continue
+		}
+		taken[snapops.TargetName(cfg, s.id)] = s.created
+		if l.NewestSource == nil || s.created.After(*l.NewestSource) {
+			l.NewestSource = s.created
+		}
+	}
+
+	for _, c := range copies {
+		if !c.available {
+			continue
+		}
+		t := c.created
+		if st, ok := taken[c.id]; ok {
+			t = st
+		}
+		if t != nil && (l.NewestCopy == nil || t.After(*l.NewestCopy)) {
+			l.NewestCopy = t
+		}
+	}
+
+	if l.NewestCopy == nil {
+		l.Breached = true
+		l.Error = fmt.Sprintf("no copies in %s", cfg.TargetRegion)
+		return l
+	}
+	l.Age = now.Sub(*l.NewestCopy)
+	if l.NewestSource != nil && l.NewestSource.After(*l.NewestCopy) {
+		l.Lag = l.NewestSource.Sub(*l.NewestCopy)
+	}
+	l.Breached = cfg.RPO > 0 && l.Age > cfg.RPO
+	return l
+}
+
+// snaps converts rds instance snapshots for lagOf
+func snaps(all []*rds.DBSnapshot) []snap {
+	var r []snap
+	for _, s := range all {
+		r = append(r, snap{id: aws.StringValue(s.DBSnapshotIdentifier), created: s.SnapshotCreateTime, available: aws.StringValue(s.Status) == "available"})
+	}
+	return r
+}
+
+// clusterSnaps converts Aurora cluster snapshots for lagOf
+func clusterSnaps(all []*rds.DBClusterSnapshot) []snap {
+	var r []snap
+	for _, s := range all {
+		r = append(r, snap{id: aws.StringValue(s.DBClusterSnapshotIdentifier), created: s.SnapshotCreateTime, available: aws.StringValue(s.Status) == "available"})
+	}
+	return r
+}
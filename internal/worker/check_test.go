@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+func TestLagOf(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2019, 3, 31, 12, 0, 0, 0, time.UTC)
+	at := func(hoursAgo int) *time.Time {
+		r := now.Add(-time.Duration(hoursAgo) * time.Hour)
+		return &r
+	}
+	cfg := wiring.Config{SourceRegion: "ap-southeast-2", TargetRegion: "us-west-2", RPO: 26 * time.Hour}
+
+	source := []snap{
+		{id: "rds:db-1", created: at(50), available: true},
+		{id: "rds:db-2", created: at(26), available: true},
+		{id: "rds:db-3", created: at(2), available: true},
+		{id: "rds:db-4", created: at(1), available: false},
+	}
+
+	tests := []struct {
+		name     string
+		copies   []snap
+		wantAge  time.Duration
+		wantLag  time.Duration
+		breached bool
+	}{
+		{
+			name:    "LagOf_up_to_date",
+			copies:  []snap{{id: "db-2-cf-ap-southeast-2", created: at(20), available: true}, {id: "db-3-cf-ap-southeast-2", created: at(1), available: true}},
+			wantAge: 2 * time.Hour,
+		},
+		{
+			name:    "LagOf_behind",
+			copies:  []snap{{id: "db-2-cf-ap-southeast-2", created: at(20), available: true}, {id: "db-3-cf-ap-southeast-2", created: at(1), available: false}},
+			wantAge: 26 * time.Hour,
+			wantLag: 24 * time.Hour,
+		},
+		{
+			name:     "LagOf_breached",
+			copies:   []snap{{id: "db-1-cf-ap-southeast-2", created: at(40), available: true}},
+			wantAge:  50 * time.Hour,
+			wantLag:  48 * time.Hour,
+			breached: true,
+		},
+		{
+			name:     "LagOf_source_gone",
+			copies:   []snap{{id: "db-0-cf-ap-southeast-2", created: at(30), available: true}},
+			wantAge:  30 * time.Hour,
+			wantLag:  28 * time.Hour,
+			breached: true,
+		},
+		{
+			name:     "LagOf_no_copies",
+			copies:   nil,
+			breached: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := lagOf(&cfg, "db", source, tt.copies, now)
+
+			if got.Age != tt.wantAge || got.Lag != tt.wantLag || got.Breached != tt.breached {
+				t.Errorf("%v = age %v lag %v breached %v, want age %v lag %v breached %v", tt.name, got.Age, got.Lag, got.Breached, tt.wantAge, tt.wantLag, tt.breached)
+			}
+		})
+	}
+}
@@ -0,0 +1,511 @@
+package worker
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	"go.uber.org/zap"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/events"
+	"github.com/bluebenno/rds-snapshot-copier/internal/fakerds"
+	"github.com/bluebenno/rds-snapshot-copier/internal/snapops"
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+// useCloud points the worker at a fake RDS, polling it without delay, until the returned func is called. The tests
+// using it swap package vars, so must not be run in parallel.
+func useCloud(c *fakerds.Cloud) func() {
+	session, caller, poll, qpoll := newSession, callerAccount, pollEvery, quotaPoll
+	newSession = func(cfg *wiring.Config, region, role string) (rdsiface.RDSAPI, error) {
+		return c.Region(region), nil
+	}
+	callerAccount = func(ctx context.Context, cfg *wiring.Config) (string, error) {
+		return fakerds.Account, nil
+	}
+	pollEvery, quotaPoll = time.Millisecond, time.Millisecond
+	return func() { newSession, callerAccount, pollEvery, quotaPoll = session, caller, poll, qpoll }
+}
+
+// startCopy starts a copy of a db-1 snapshot to us-west-2 as this tool would, and describes the region steps times
+func startCopy(c *fakerds.Cloud, snap string, steps int) {
+	_, err := c.Region("us-west-2").CopyDBSnapshotWithContext(context.Background(), &rds.CopyDBSnapshotInput{
+		SourceDBSnapshotIdentifier: aws.String(fakerds.ARN("ap-southeast-2", "snapshot", snap)),
+		TargetDBSnapshotIdentifier: aws.String(snapops.TargetName(&wiring.Config{SourceRegion: "ap-southeast-2"}, snap)),
+		Tags:                       []*rds.Tag{{Key: aws.String(snapops.SourceTag), Value: aws.String(snap)}},
+	})
+	if err != nil {
+		panic(err)
+	}
+	for i := 0; i < steps; i++ {
+		c.Region("us-west-2").DescribeDBSnapshotsWithContext(context.Background(), &rds.DescribeDBSnapshotsInput{})
+	}
+}
+
+// results returns the Result of each outcome in a report, by source snapshot
+func results(r *RunReport) map[string]string {
+	res := make(map[string]string)
+	for _, o := range r.Outcomes {
+		if o.Snapshot != "" {
+			res[o.Snapshot] = o.Result
+		}
+	}
+	return res
+}
+
+// statuses returns the status of each snapshot in a region, by identifier
+func statuses(c *fakerds.Cloud, region string) map[string]string {
+	res := make(map[string]string)
+	for _, s := range c.Snapshots(region) {
+		res[*s.DBSnapshotIdentifier] = *s.Status
+	}
+	for _, s := range c.ClusterSnapshots(region) {
+		res[*s.DBClusterSnapshotIdentifier] = *s.Status
+	}
+	return res
+}
+
+func TestCycleEndToEnd(t *testing.T) {
+	day := 24 * time.Hour
+	now := time.Now().Truncate(time.Second)
+	const src, dst = "ap-southeast-2", "us-west-2"
+
+	tests := []struct {
+		name    string
+		setup   func(c *fakerds.Cloud)
+		cfg     func(cfg *wiring.Config)
+		err     bool
+		results map[string]string // Outcomes, by source snapshot
+		target  map[string]string // Snapshots in the target region afterwards, by identifier
+		check   func(t *testing.T, c *fakerds.Cloud, r *RunReport)
+	}{
+		{
+			name: "Cycle_copiesLatest",
+			setup: func(c *fakerds.Cloud) {
+				c.AddSnapshot(src, "db-1", "rds:db-1-2019-03-30", now.Add(-day))
+				c.AddSnapshot(src, "db-1", "rds:db-1-2019-03-29", now.Add(-2*day))
+				c.AddInstance(src, "db-2", nil) // Not tagged
+				c.AddSnapshot(src, "db-2", "rds:db-2-2019-03-30", now.Add(-day))
+			},
+			results: map[string]string{"rds:db-1-2019-03-30": "available"},
+			target:  map[string]string{"db-1-2019-03-30-cf-ap-southeast-2": "available"},
+		},
+		{
+			name: "Cycle_clusters",
+			setup: func(c *fakerds.Cloud) {
+				c.AddCluster(src, "aurora-1", map[string]string{"copy": "true"})
+				c.AddClusterSnapshot(src, "aurora-1", "rds:aurora-1-2019-03-30", now.Add(-day))
+			},
+			cfg:     func(cfg *wiring.Config) { cfg.SnapshotType = "all" },
+			results: map[string]string{"rds:db-1-2019-03-30": "available", "rds:aurora-1-2019-03-30": "available"},
+			target:  map[string]string{"db-1-2019-03-30-cf-ap-southeast-2": "available", "aurora-1-2019-03-30-cf-ap-southeast-2": "available"},
+		},
+		{
+			name: "Cycle_alreadyCopied",
+			setup: func(c *fakerds.Cloud) {
+				c.AddSnapshot(dst, "db-1", "db-1-2019-03-30-cf-ap-southeast-2", now.Add(-day))
+			},
+			results: map[string]string{"rds:db-1-2019-03-30": "skipped"},
+			target:  map[string]string{"db-1-2019-03-30-cf-ap-southeast-2": "available"},
+			check: func(t *testing.T, c *fakerds.Cloud, r *RunReport) {
+				if n := c.Calls(dst, "CopyDBSnapshot"); n != 0 {
+					t.Errorf("CopyDBSnapshot calls = %v, want %v", n, 0)
+				}
+			},
+		},
+		{
+			name:    "Cycle_failedCopyDeleted",
+			setup:   func(c *fakerds.Cloud) { c.FailCopy(dst, "db-1-2019-03-30-cf-ap-southeast-2") },
+			err:     true,
+			results: map[string]string{"rds:db-1-2019-03-30": "failed"},
+			target:  map[string]string{},
+		},
+		{
+			name:    "Cycle_resumesCopyInProgress",
+			setup:   func(c *fakerds.Cloud) { startCopy(c, "rds:db-1-2019-03-30", 0) },
+			results: map[string]string{"rds:db-1-2019-03-30": "available"},
+			target:  map[string]string{"db-1-2019-03-30-cf-ap-southeast-2": "available"},
+			check: func(t *testing.T, c *fakerds.Cloud, r *RunReport) {
+				if n := c.Calls(dst, "CopyDBSnapshot"); n != 1 {
+					t.Errorf("CopyDBSnapshot calls = %v, want %v", n, 1)
+				}
+				if got := c.Tags(dst, fakerds.ARN(dst, "snapshot", "db-1-2019-03-30-cf-ap-southeast-2")); len(got) != 0 {
+					t.Errorf("Tags = %v, want none", got)
+				}
+			},
+		},
+		{
+			name:    "Cycle_reconcilesFinishedCopy",
+			setup:   func(c *fakerds.Cloud) { startCopy(c, "rds:db-1-2019-03-30", 3) },
+			results: map[string]string{"rds:db-1-2019-03-30": "available"},
+			target:  map[string]string{"db-1-2019-03-30-cf-ap-southeast-2": "available"},
+			check: func(t *testing.T, c *fakerds.Cloud, r *RunReport) {
+				if n := len(r.Outcomes); n != 1 {
+					t.Errorf("Outcomes = %v, want only the copy", r.Outcomes)
+				}
+				if got := c.Tags(dst, fakerds.ARN(dst, "snapshot", "db-1-2019-03-30-cf-ap-southeast-2")); len(got) != 0 {
+					t.Errorf("Tags = %v, want none", got)
+				}
+			},
+		},
+		{
+			name: "Cycle_reconcilesFailedCopy",
+			setup: func(c *fakerds.Cloud) {
+				c.FailCopy(dst, "db-1-2019-03-30-cf-ap-southeast-2")
+				startCopy(c, "rds:db-1-2019-03-30", 3)
+			},
+			err:     true,
+			results: map[string]string{"rds:db-1-2019-03-30": "failed"},
+			target:  map[string]string{},
+		},
+		{
+			name: "Cycle_untaggedCopyFinishedWith",
+			setup: func(c *fakerds.Cloud) {
+				startCopy(c, "rds:db-1-2019-03-30", 3)
+				c.Region(dst).RemoveTagsFromResourceWithContext(context.Background(), &rds.RemoveTagsFromResourceInput{
+					ResourceName: aws.String(fakerds.ARN(dst, "snapshot", "db-1-2019-03-30-cf-ap-southeast-2")),
+					TagKeys:      aws.StringSlice([]string{snapops.SourceTag}),
+				})
+			},
+			results: map[string]string{"rds:db-1-2019-03-30": "skipped"},
+			target:  map[string]string{"db-1-2019-03-30-cf-ap-southeast-2": "available"},
+		},
+		{
+			name: "Cycle_quotaExceededBacksOff",
+			setup: func(c *fakerds.Cloud) {
+				c.Fail(dst, "CopyDBSnapshot", awserr.New(rds.ErrCodeSnapshotQuotaExceededFault, "quota exceeded", nil), 2)
+			},
+			results: map[string]string{"rds:db-1-2019-03-30": "available"},
+			target:  map[string]string{"db-1-2019-03-30-cf-ap-southeast-2": "available"},
+			check: func(t *testing.T, c *fakerds.Cloud, r *RunReport) {
+				if n := c.Calls(dst, "CopyDBSnapshot"); n != 3 {
+					t.Errorf("CopyDBSnapshot calls = %v, want %v", n, 3)
+				}
+			},
+		},
+		{
+			name: "Cycle_lookbackAndHousekeeping",
+			setup: func(c *fakerds.Cloud) {
+				c.AddSnapshot(src, "db-1", "rds:db-1-2019-03-29", now.Add(-2*day))
+				c.AddSnapshot(src, "db-1", "rds:db-1-2019-03-28", now.Add(-3*day-time.Hour)) // Outside the lookback
+				c.AddSnapshot(dst, "db-1", "db-1-2019-03-27-cf-ap-southeast-2", now.Add(-4*day))
+			},
+			cfg:     func(cfg *wiring.Config) { cfg.Lookback, cfg.MaxSnap = 72*time.Hour, 2 },
+			results: map[string]string{"rds:db-1-2019-03-30": "available", "rds:db-1-2019-03-29": "available"},
+			target:  map[string]string{"db-1-2019-03-30-cf-ap-southeast-2": "available", "db-1-2019-03-29-cf-ap-southeast-2": "available"},
+			check: func(t *testing.T, c *fakerds.Cloud, r *RunReport) {
+				if r.Deleted[dst] != 1 {
+					t.Errorf("Deleted = %v, want %v", r.Deleted[dst], 1)
+				}
+			},
+		},
+		{
+			name: "Cycle_lookbackFillsGap",
+			setup: func(c *fakerds.Cloud) {
+				c.AddSnapshot(src, "db-1", "rds:db-1-2019-03-29", now.Add(-2*day))
+				c.AddSnapshot(dst, "db-1", "db-1-2019-03-30-cf-ap-southeast-2", now.Add(-time.Hour)) // Copied, but not the one before
+			},
+			cfg:     func(cfg *wiring.Config) { cfg.Lookback = 7 * day },
+			results: map[string]string{"rds:db-1-2019-03-29": "available"},
+			target:  map[string]string{"db-1-2019-03-30-cf-ap-southeast-2": "available", "db-1-2019-03-29-cf-ap-southeast-2": "available"},
+		},
+		{
+			name:    "Cycle_noWait",
+			cfg:     func(cfg *wiring.Config) { cfg.NoWait = true },
+			results: map[string]string{"rds:db-1-2019-03-30": "handed_off"},
+			target:  map[string]string{"db-1-2019-03-30-cf-ap-southeast-2": "copying"},
+		},
+		{
+			name:  "Cycle_crossAccountShares",
+			setup: func(c *fakerds.Cloud) { c.AddSnapshot(src, "db-1", "db-1-manual", now) },
+			cfg: func(cfg *wiring.Config) {
+				cfg.TargetRoleARN = "arn:aws:iam::210987654321:role/copier"
+			},
+			results: map[string]string{"db-1-manual": "available"},
+			target:  map[string]string{"db-1-manual-cf-ap-southeast-2": "available"},
+			check: func(t *testing.T, c *fakerds.Cloud, r *RunReport) {
+				if n := c.Calls(src, "ModifyDBSnapshotAttribute"); n != 2 {
+					t.Errorf("ModifyDBSnapshotAttribute calls = %v, want %v", n, 2)
+				}
+				if got := c.Shared(src, "db-1-manual"); len(got) != 0 {
+					t.Errorf("Shared = %v, want none", got)
+				}
+			},
+		},
+		{
+			name: "Cycle_crossAccountAutomated",
+			cfg: func(cfg *wiring.Config) {
+				cfg.TargetRoleARN = "arn:aws:iam::210987654321:role/copier"
+			},
+			results: map[string]string{"rds:db-1-2019-03-30": "available"},
+			target:  map[string]string{"db-1-2019-03-30-cf-ap-southeast-2": "available"},
+			check: func(t *testing.T, c *fakerds.Cloud, r *RunReport) {
+				// Shared as a manual copy, deleted once copied
+				if n := c.Calls(src, "CopyDBSnapshot"); n != 1 {
+					t.Errorf("CopyDBSnapshot calls = %v, want %v", n, 1)
+				}
+				if n := c.Calls(src, "ModifyDBSnapshotAttribute"); n != 2 {
+					t.Errorf("ModifyDBSnapshotAttribute calls = %v, want %v", n, 2)
+				}
+				if got, ok := statuses(c, src)["db-1-2019-03-30-share-us-west-2"]; ok {
+					t.Errorf("manual copy to share = %v, want deleted", got)
+				}
+			},
+		},
+		{
+			name: "Cycle_sameAccountRole",
+			cfg: func(cfg *wiring.Config) {
+				cfg.TargetRoleARN = "arn:aws:iam::" + fakerds.Account + ":role/copier"
+			},
+			results: map[string]string{"rds:db-1-2019-03-30": "available"},
+			target:  map[string]string{"db-1-2019-03-30-cf-ap-southeast-2": "available"},
+			check: func(t *testing.T, c *fakerds.Cloud, r *RunReport) {
+				if n := c.Calls(src, "CopyDBSnapshot") + c.Calls(src, "ModifyDBSnapshotAttribute"); n != 0 {
+					t.Errorf("source CopyDBSnapshot and ModifyDBSnapshotAttribute calls = %v, want %v", n, 0)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fakerds.New()
+			defer useCloud(c)()
+			c.AddInstance(src, "db-1", map[string]string{"copy": "true"})
+			c.AddSnapshot(src, "db-1", "rds:db-1-2019-03-30", now.Add(-day))
+			if tt.setup != nil {
+				tt.setup(c)
+			}
+			cfg := &wiring.Config{SourceRegion: src, TargetRegion: dst, Tag: "copy", MaxCopyInFlight: 2}
+			if tt.cfg != nil {
+				tt.cfg(cfg)
+			}
+
+			r, err := Cycle(context.Background(), zap.NewNop(), cfg)
+			if (err != nil) != tt.err {
+				t.Fatalf("Cycle() error = %v, want error %v", err, tt.err)
+			}
+			got := results(r)
+			if len(got) != len(tt.results) {
+				t.Errorf("results = %v, want %v", got, tt.results)
+			}
+			for k, v := range tt.results {
+				if got[k] != v {
+					t.Errorf("results[%v] = %v, want %v", k, got[k], v)
+				}
+			}
+			target := statuses(c, dst)
+			if len(target) != len(tt.target) {
+				t.Errorf("target = %v, want %v", target, tt.target)
+			}
+			for k, v := range tt.target {
+				if target[k] != v {
+					t.Errorf("target[%v] = %v, want %v", k, target[k], v)
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, c, r)
+			}
+		})
+	}
+}
+
+func TestHandOffEndToEnd(t *testing.T) {
+	c := fakerds.New()
+	defer useCloud(c)()
+	c.AddInstance("ap-southeast-2", "db-1", map[string]string{"copy": "true"})
+	c.AddSnapshot("ap-southeast-2", "db-1", "rds:db-1-2019-03-30", time.Now().Add(-time.Hour))
+	copyARN := fakerds.ARN("us-west-2", "snapshot", "db-1-2019-03-30-cf-ap-southeast-2")
+
+	cfg := &wiring.Config{SourceRegion: "ap-southeast-2", TargetRegion: "us-west-2", Tag: "copy", MaxCopyInFlight: 1,
+		TargetRoleARN: "arn:aws:iam::210987654321:role/copier", NoWait: true}
+	r, err := Cycle(context.Background(), zap.NewNop(), cfg)
+	if err != nil {
+		t.Fatalf("Cycle() = %v, want nil", err)
+	}
+	if got := results(r)["rds:db-1-2019-03-30"]; got != "handed_off" {
+		t.Errorf("result = %v, want %v", got, "handed_off")
+	}
+	// Not waiting for the manual copy to share either, which records what it is a copy of
+	shareARN := fakerds.ARN("ap-southeast-2", "snapshot", "db-1-2019-03-30-share-us-west-2")
+	if got, want := c.Tags("ap-southeast-2", shareARN), map[string]string{snapops.SourceTag: "rds:db-1-2019-03-30"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("manual copy to share Tags = %v, want %v", got, want)
+	}
+	if got := len(c.Snapshots("us-west-2")); got != 0 {
+		t.Errorf("len(Snapshots(us-west-2)) = %v, want %v", got, 0)
+	}
+
+	// The manual copy finishes before the next run, which shares it and starts the copy
+	for i := 0; i < 3 && statuses(c, "ap-southeast-2")["db-1-2019-03-30-share-us-west-2"] != "available"; i++ {
+		c.Region("ap-southeast-2").DescribeDBSnapshotsWithContext(context.Background(), &rds.DescribeDBSnapshotsInput{})
+	}
+	r, err = Cycle(context.Background(), zap.NewNop(), cfg)
+	if err != nil {
+		t.Fatalf("Cycle() = %v, want nil", err)
+	}
+	if got := results(r)["rds:db-1-2019-03-30"]; got != "handed_off" {
+		t.Errorf("result = %v, want %v", got, "handed_off")
+	}
+	if len(r.Outcomes) != 1 {
+		t.Errorf("Outcomes = %+v, want the one copy, taken up once", r.Outcomes)
+	}
+	if n := c.Calls("ap-southeast-2", "CopyDBSnapshot"); n != 1 {
+		t.Errorf("source CopyDBSnapshot calls = %v, want %v", n, 1)
+	}
+	if got := c.Shared("ap-southeast-2", "db-1-2019-03-30-share-us-west-2"); len(got) != 1 {
+		t.Errorf("Shared = %v, want the target account", got)
+	}
+	// The copy records what to clean up once it is done
+	want := map[string]string{snapops.SourceTag: "rds:db-1-2019-03-30", snapops.ShareTag: "db-1-2019-03-30-share-us-west-2"}
+	if got := c.Tags("us-west-2", copyARN); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags = %v, want %v", got, want)
+	}
+
+	// The copy finishes before the next run, which reports it and cleans up after it
+	c.Region("us-west-2").DescribeDBSnapshotsWithContext(context.Background(), &rds.DescribeDBSnapshotsInput{})
+	c.Region("us-west-2").DescribeDBSnapshotsWithContext(context.Background(), &rds.DescribeDBSnapshotsInput{})
+	cfg.NoWait = false
+	r, err = Cycle(context.Background(), zap.NewNop(), cfg)
+	if err != nil {
+		t.Fatalf("Cycle() = %v, want nil", err)
+	}
+	if got := results(r)["rds:db-1-2019-03-30"]; got != "available" {
+		t.Errorf("result = %v, want %v", got, "available")
+	}
+	if n := c.Calls("us-west-2", "CopyDBSnapshot"); n != 1 {
+		t.Errorf("CopyDBSnapshot calls = %v, want %v", n, 1)
+	}
+	if got, ok := statuses(c, "ap-southeast-2")["db-1-2019-03-30-share-us-west-2"]; ok {
+		t.Errorf("manual copy to share = %v, want deleted", got)
+	}
+	if got := c.Tags("us-west-2", copyARN); len(got) != 0 {
+		t.Errorf("Tags = %v, want none", got)
+	}
+}
+
+func TestLooperEndToEnd(t *testing.T) {
+	c := fakerds.New()
+	defer useCloud(c)()
+	c.AddInstance("ap-southeast-2", "db-1", map[string]string{"copy": "us-west-2+eu-west-1"})
+	c.AddSnapshot("ap-southeast-2", "db-1", "rds:db-1-2019-03-30", time.Now().Add(-time.Hour))
+	c.Throttle("us-west-2", "DescribeDBSnapshots", 2) // The first target lookups fail, so it is skipped this cycle
+
+	cfg := &wiring.Config{SourceRegion: "ap-southeast-2", TargetRegion: "us-west-2", Tag: "copy", MaxCopyInFlight: 1, Once: true}
+	if err := Looper(context.Background(), zap.NewNop(), cfg, nil); err != nil {
+		t.Fatalf("Looper() = %v, want nil", err)
+	}
+	for region, want := range map[string]int{"us-west-2": 0, "eu-west-1": 1} {
+		if got := len(c.Snapshots(region)); got != want {
+			t.Errorf("len(Snapshots(%v)) = %v, want %v", region, got, want)
+		}
+	}
+
+	// The next cycle copies what was missed
+	if err := Looper(context.Background(), zap.NewNop(), cfg, nil); err != nil {
+		t.Fatalf("Looper() = %v, want nil", err)
+	}
+	if got := statuses(c, "us-west-2")["db-1-2019-03-30-cf-ap-southeast-2"]; got != "available" {
+		t.Errorf("us-west-2 copy = %v, want %v", got, "available")
+	}
+}
+
+func TestCopyForEndToEnd(t *testing.T) {
+	c := fakerds.New()
+	defer useCloud(c)()
+	c.AddInstance("ap-southeast-2", "db-1", map[string]string{"copy": "true"})
+	c.AddSnapshot("ap-southeast-2", "db-1", "rds:db-1-2019-03-30", time.Now().Add(-time.Hour))
+
+	cfg := &wiring.Config{SourceRegion: "ap-southeast-2", TargetRegion: "us-west-2", Tag: "copy", MaxCopyInFlight: 1}
+	r, err := CopyFor(context.Background(), zap.NewNop(), cfg, &events.Event{Snapshot: "rds:db-1-2019-03-30", Region: "ap-southeast-2"})
+	if err != nil {
+		t.Fatalf("CopyFor() = %v, want nil", err)
+	}
+	if got := results(r)["rds:db-1-2019-03-30"]; got != "available" {
+		t.Errorf("result = %v, want %v", got, "available")
+	}
+}
+
+func TestProbeEndToEnd(t *testing.T) {
+	c := fakerds.New()
+	defer useCloud(c)()
+
+	cfg := &wiring.Config{SourceRegion: "ap-southeast-2", TargetRegion: "us-west-2"}
+	if err := probe(context.Background(), cfg); err != nil {
+		t.Errorf("probe() = %v, want nil", err)
+	}
+	c.Fail("ap-southeast-2", "DescribeDBInstances", awserr.New("AccessDenied", "not authorized", nil), 1)
+	if err := probe(context.Background(), cfg); err == nil {
+		t.Error("probe() = nil, want the error calling AWS")
+	}
+}
+
+// raceCopy is the RDS API of a region where every copy is started by something else just before ours
+type raceCopy struct {
+	rdsiface.RDSAPI
+}
+
+func (r raceCopy) CopyDBSnapshotWithContext(ctx aws.Context, in *rds.CopyDBSnapshotInput, opts ...request.Option) (*rds.CopyDBSnapshotOutput, error) {
+	if _, err := r.RDSAPI.CopyDBSnapshotWithContext(ctx, in, opts...); err != nil {
+		return nil, err
+	}
+	return nil, awserr.New(rds.ErrCodeDBSnapshotAlreadyExistsFault, "already exists", nil)
+}
+
+func TestCopyAlreadyStartedEndToEnd(t *testing.T) {
+	c := fakerds.New()
+	defer useCloud(c)()
+	newSession = func(cfg *wiring.Config, region, role string) (rdsiface.RDSAPI, error) {
+		if region == "us-west-2" {
+			return raceCopy{c.Region(region)}, nil
+		}
+		return c.Region(region), nil
+	}
+	c.AddInstance("ap-southeast-2", "db-1", map[string]string{"copy": "true"})
+	c.AddSnapshot("ap-southeast-2", "db-1", "db-1-manual", time.Now().Add(-time.Hour))
+
+	cfg := &wiring.Config{SourceRegion: "ap-southeast-2", TargetRegion: "us-west-2", Tag: "copy", MaxCopyInFlight: 1,
+		TargetRoleARN: "arn:aws:iam::210987654321:role/copier"}
+	r, err := Cycle(context.Background(), zap.NewNop(), cfg)
+	if err != nil {
+		t.Fatalf("Cycle() = %v, want nil", err)
+	}
+	if got := results(r)["db-1-manual"]; got != "available" {
+		t.Errorf("result = %v, want %v", got, "available")
+	}
+	// Whoever started the copy stops sharing the snapshot, not us
+	if got := c.Shared("ap-southeast-2", "db-1-manual"); len(got) != 1 {
+		t.Errorf("Shared = %v, want still shared", got)
+	}
+}
+
+func TestCopyInProgressSkippedEndToEnd(t *testing.T) {
+	c := fakerds.New()
+	defer useCloud(c)()
+	c.AddInstance("ap-southeast-2", "db-1", map[string]string{"copy": "true"})
+	c.AddSnapshot("ap-southeast-2", "db-1", "rds:db-1-2019-03-30", time.Now().Add(-time.Hour))
+
+	// An event triggered copy of the same snapshot is in progress
+	release, ok := claim("us-west-2", copyjob{snapshot: &rds.DBSnapshot{DBInstanceIdentifier: aws.String("db-1"), DBSnapshotIdentifier: aws.String("rds:db-1-2019-03-30")}})
+	if !ok {
+		t.Fatal("claim() = false, want true")
+	}
+	defer release()
+
+	cfg := &wiring.Config{SourceRegion: "ap-southeast-2", TargetRegion: "us-west-2", Tag: "copy", MaxCopyInFlight: 1}
+	r, err := CopyFor(context.Background(), zap.NewNop(), cfg, &events.Event{Snapshot: "rds:db-1-2019-03-30", Region: "ap-southeast-2"})
+	if err != nil {
+		t.Fatalf("CopyFor() = %v, want nil", err)
+	}
+	if got := results(r)["rds:db-1-2019-03-30"]; got != "skipped" {
+		t.Errorf("result = %v, want %v", got, "skipped")
+	}
+	if n := c.Calls("us-west-2", "CopyDBSnapshot"); n != 0 {
+		t.Errorf("CopyDBSnapshot calls = %v, want %v", n, 0)
+	}
+}
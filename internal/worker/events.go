@@ -0,0 +1,212 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"go.uber.org/zap"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/events"
+	"github.com/bluebenno/rds-snapshot-copier/internal/rdsops"
+	"github.com/bluebenno/rds-snapshot-copier/internal/snapops"
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+// CopyFor copies the snapshots of the rds instance or Aurora cluster an event is for, as a cycle would but for it
+// alone, returning a report of what was done. Events for another region, or an rds which is not in scope, are ignored.
+// Copies already in progress in this process, e.g. started by a cycle or a duplicate event, are skipped.
+func CopyFor(ctx context.Context, logger *zap.Logger, cfg *wiring.Config, ev *events.Event) (*RunReport, error) {
+	report := NewRunReport(cfg.SourceRegion)
+	err := copyFor(ctx, logger, cfg, report, ev)
+	report.Error(err)
+	report.Finished()
+	return report, err
+}
+
+func copyFor(ctx context.Context, logger *zap.Logger, cfg *wiring.Config, report *RunReport, ev *events.Event) error {
+	if ev.Region != "" && ev.Region != cfg.SourceRegion {
+		logger.Info("Ignoring snapshot event from another region", zap.String("snapshot", ev.Snapshot), zap.String("region", ev.Region))
+		return nil
+	}
+	if (ev.Cluster && !cfg.Clusters()) || (!ev.Cluster && !cfg.Instances()) {
+		return nil
+	}
+
+	cfg, err := withSourceAccount(ctx, logger, cfg)
+	if err != nil {
+		return err
+	}
+
+	SrcRDSSource, err := newSession(cfg, cfg.SourceRegion, cfg.SourceRoleARN)
+	if err != nil {
+		logger.Error("Failed to create an AWS rds Session for the source region", zap.String("source_region", cfg.SourceRegion), zap.Error(err))
+		return err
+	}
+
+	var inscopeRDS []rdsops.InScope
+	var inscopeClusters []rdsops.InScopeCluster
+	var skipped []rdsops.Skipped
+	var id string
+	if ev.Cluster {
+		s, err := snapops.DescribeCluster(ctx, SrcRDSSource, ev.Snapshot)
+		if err != nil || s == nil {
+			return fmt.Errorf("failed to find cluster snapshot %s: %v", ev.Snapshot, err)
+		}
+		id = aws.StringValue(s.DBClusterIdentifier)
+		c, err := rdsops.GetCluster(ctx, SrcRDSSource, id)
+		if err != nil || c == nil {
+			return fmt.Errorf("failed to find Aurora cluster %s: %v", id, err)
+		}
+		inscopeClusters, skipped, err = rdsops.FilterClusters(ctx, logger, cfg, SrcRDSSource, []*rds.DBCluster{c})
+		if err != nil {
+			return err
+		}
+	} else {
+		s, err := snapops.Describe(ctx, SrcRDSSource, ev.Snapshot)
+		if err != nil || s == nil {
+			return fmt.Errorf("failed to find snapshot %s: %v", ev.Snapshot, err)
+		}
+		id = aws.StringValue(s.DBInstanceIdentifier)
+		i, err := rdsops.Get(ctx, SrcRDSSource, id)
+		if err != nil || i == nil {
+			return fmt.Errorf("failed to find rds instance %s: %v", id, err)
+		}
+		inscopeRDS, skipped, err = rdsops.Filter(ctx, logger, cfg, SrcRDSSource, []*rds.DBInstance{i})
+		if err != nil {
+			return err
+		}
+	}
+	for _, s := range skipped {
+		report.Skip(s.ID, "", "", s.Reason)
+	}
+	if len(inscopeRDS) == 0 && len(inscopeClusters) == 0 {
+		return nil
+	}
+
+	logger.Info("Copying on snapshot event", zap.String("rds", id), zap.String("snapshot", ev.Snapshot))
+	return copyAll(ctx, logger, report, cfg, SrcRDSSource, inscopeRDS, inscopeClusters)
+}
+
+// handleEvent runs CopyFor, logging and writing its report as a cycle would
+func handleEvent(ctx context.Context, logger *zap.Logger, cfg *wiring.Config, ev *events.Event) {
+	report, err := CopyFor(ctx, logger, cfg, ev)
+	report.Log(logger)
+	if cfg.Report != "" {
+		if werr := report.Write(cfg.Report); werr != nil {
+			logger.Warn("Failed to write the run report", zap.String("report", cfg.Report), zap.Error(werr))
+		}
+	}
+	if err != nil && ctx.Err() == nil {
+		logger.Error("Event triggered copy failed", zap.String("snapshot", ev.Snapshot), zap.Error(err))
+	}
+}
+
+// maxEvents is the most snapshot events handled at once. Their copies also wait for a slot in the target region
+const maxEvents = 10
+
+// eventSlots bounds the snapshot events handled at once, each in the background
+type eventSlots chan struct{}
+
+// wait handles ev in the background once a slot is free, unless ctx is done first
+func (s eventSlots) wait(ctx context.Context, handle func(*events.Event), ev *events.Event) {
+	select {
+	case s <- struct{}{}:
+		go s.handle(handle, ev)
+	case <-ctx.Done():
+	}
+}
+
+// try handles ev in the background if a slot is free, returning false if not
+func (s eventSlots) try(handle func(*events.Event), ev *events.Event) bool {
+	select {
+	case s <- struct{}{}:
+		go s.handle(handle, ev)
+		return true
+	default:
+		return false
+	}
+}
+
+func (s eventSlots) handle(handle func(*events.Event), ev *events.Event) {
+	defer func() { <-s }()
+	handle(ev)
+}
+
+// consume receives RDS events from an SQS queue until ctx is cancelled, passing each to handle, which may block
+// until it has room for more. Messages are deleted once received; should a copy fail, the next cycle retries it.
+func consume(ctx context.Context, logger *zap.Logger, q sqsiface.SQSAPI, queueURL string, handle func(*events.Event)) {
+	logger.Info("Consuming snapshot events", zap.String("queue", queueURL))
+	for ctx.Err() == nil {
+		out, err := q.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(queueURL),
+			MaxNumberOfMessages: aws.Int64(10),
+			WaitTimeSeconds:     aws.Int64(20),
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			logger.Warn("Failed to receive snapshot events", zap.String("queue", queueURL), zap.Error(err))
+			select {
+			case <-ctx.Done():
+			case <-time.After(10 * time.Second):
+			}
+			continue
+		}
+
+		for _, m := range out.Messages {
+			ev, err := events.Parse([]byte(aws.StringValue(m.Body)))
+			if err != nil {
+				logger.Warn("Discarding invalid snapshot event", zap.String("queue", queueURL), zap.String("message_id", aws.StringValue(m.MessageId)), zap.Error(err))
+			}
+			if _, err := q.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{QueueUrl: aws.String(queueURL), ReceiptHandle: m.ReceiptHandle}); err != nil {
+				logger.Warn("Failed to delete snapshot event", zap.String("queue", queueURL), zap.String("message_id", aws.StringValue(m.MessageId)), zap.Error(err))
+			}
+			if ev != nil {
+				handle(ev)
+			}
+		}
+	}
+	logger.Info("Stopped consuming snapshot events", zap.String("queue", queueURL))
+}
+
+// webhook accepts an RDS event POSTed as JSON, passing it to handle. It responds 202 if the event is a snapshot
+// being created, 200 if it is some other RDS event, 400 if it is not an RDS event, and 503 if handle has no room
+// for it, so the sender retries it later.
+func webhook(handle func(*events.Event) bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "POST an RDS event", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ev, err := events.Parse(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if ev == nil {
+			fmt.Fprintln(w, "ignored")
+			return
+		}
+		if !handle(ev) {
+			w.Header().Set("Retry-After", "60")
+			http.Error(w, "too many snapshot events in progress", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintln(w, "accepted")
+	}
+}
@@ -0,0 +1,154 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"go.uber.org/zap"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/events"
+)
+
+const snapshotCreated = `{"source":"aws.rds","detail-type":"RDS DB Snapshot Event","region":"ap-southeast-2",
+	"detail":{"SourceType":"SNAPSHOT","SourceIdentifier":"rds:db-1-2019-03-30-00-05","Message":"Automated snapshot created"}}`
+
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+
+	var got []*events.Event
+	busy := false
+	hf := webhook(func(ev *events.Event) bool {
+		if busy {
+			return false
+		}
+		got = append(got, ev)
+		return true
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		busy   bool
+		want   int
+	}{
+		{"Webhook_snapshot_created", "POST", snapshotCreated, false, http.StatusAccepted},
+		{"Webhook_busy", "POST", snapshotCreated, true, http.StatusServiceUnavailable},
+		{"Webhook_other_event", "POST", `{"source":"aws.rds","detail":{"SourceType":"DB_INSTANCE","Message":"DB instance restarted"}}`, false, http.StatusOK},
+		{"Webhook_not_an_event", "POST", `{}`, false, http.StatusBadRequest},
+		{"Webhook_get", "GET", "", false, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		busy = tt.busy
+		rec := httptest.NewRecorder()
+		hf(rec, httptest.NewRequest(tt.method, "/events", strings.NewReader(tt.body)))
+		if rec.Code != tt.want {
+			t.Errorf("%v = %d, want %d: %s", tt.name, rec.Code, tt.want, rec.Body.String())
+		}
+	}
+
+	if len(got) != 1 || got[0].Snapshot != "rds:db-1-2019-03-30-00-05" {
+		t.Errorf("webhook() handled %+v, want the one snapshot created", got)
+	}
+}
+
+// mockSQSClient is a queue holding messages, which cancels ctx once they have all been received
+type mockSQSClient struct {
+	sqsiface.SQSAPI
+	mu       sync.Mutex
+	messages []*sqs.Message
+	deleted  []string
+	cancel   func()
+}
+
+// Mock ReceiveMessageWithContext
+func (m *mockSQSClient) ReceiveMessageWithContext(ctx aws.Context, i *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.messages) == 0 {
+		m.cancel()
+		return nil, ctx.Err()
+	}
+	out := &sqs.ReceiveMessageOutput{Messages: m.messages}
+	m.messages = nil
+	return out, nil
+}
+
+// Mock DeleteMessageWithContext
+func (m *mockSQSClient) DeleteMessageWithContext(ctx aws.Context, i *sqs.DeleteMessageInput, opts ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleted = append(m.deleted, aws.StringValue(i.ReceiptHandle))
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func TestConsume(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q := &mockSQSClient{cancel: cancel, messages: []*sqs.Message{
+		{ReceiptHandle: aws.String("1"), Body: aws.String(snapshotCreated)},
+		{ReceiptHandle: aws.String("2"), Body: aws.String("not json")},
+		{ReceiptHandle: aws.String("3"), Body: aws.String(`{"Event Source":"db-snapshot","Source ID":"db-1-manual","Event Message":"Deleted manual snapshot"}`)},
+	}}
+
+	var got []*events.Event
+	consume(ctx, zap.NewNop(), q, "http://localhost:9324/queue/events", func(ev *events.Event) { got = append(got, ev) })
+
+	if len(got) != 1 || got[0].Snapshot != "rds:db-1-2019-03-30-00-05" {
+		t.Errorf("consume() handled %+v, want the one snapshot created", got)
+	}
+	if strings.Join(q.deleted, ",") != "1,2,3" {
+		t.Errorf("consume() deleted %v, want every message", q.deleted)
+	}
+}
+
+func TestEventSlots(t *testing.T) {
+	t.Parallel()
+
+	slots := make(eventSlots, 1)
+	started, finish := make(chan string, 2), make(chan struct{})
+	handle := func(ev *events.Event) {
+		started <- ev.Snapshot
+		<-finish
+	}
+
+	if !slots.try(handle, &events.Event{Snapshot: "s1"}) {
+		t.Fatal("try() with a free slot = false, want true")
+	}
+	<-started
+	if slots.try(handle, &events.Event{Snapshot: "s2"}) {
+		t.Error("try() with no free slot = true, want false")
+	}
+
+	// wait gives up once ctx is done, and otherwise handles the event once the slot is free
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	slots.wait(ctx, handle, &events.Event{Snapshot: "s3"})
+	done := make(chan struct{})
+	go func() {
+		slots.wait(context.Background(), handle, &events.Event{Snapshot: "s4"})
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("wait() returned with no free slot")
+	case <-time.After(10 * time.Millisecond):
+	}
+	finish <- struct{}{}
+	<-done
+	if got := <-started; got != "s4" {
+		t.Errorf("wait() handled %s, want s4", got)
+	}
+	close(finish)
+}
@@ -0,0 +1,115 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+// health tracks whether the copier is live and ready, for the /healthz and /readyz endpoints
+type health struct {
+	mu        sync.Mutex
+	started   time.Time
+	lastCycle time.Time // When a cycle last completed, successfully or not
+
+	rpoRunning bool      // If the RPO check is running
+	rpoChecked bool      // If the RPO check has run
+	rpoCycle   time.Time // The lastCycle the RPO check last ran after
+	rpoErr     error     // The outcome of the last RPO check
+
+	probing  sync.Mutex // Held while probing AWS, so concurrent readiness checks share a probe
+	probed   time.Time  // When AWS was last probed
+	probeErr error      // The outcome of the last probe
+}
+
+// status is the health of this process
+var status = newHealth()
+
+// readyTTL is how long the outcome of probing AWS is reused for, so frequent readiness checks do not each call it
+var readyTTL = 30 * time.Second
+
+func newHealth() *health {
+	return &health{started: time.Now()}
+}
+
+// cycled records that a cycle has completed
+func (h *health) cycled() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastCycle = time.Now()
+}
+
+// live returns an error if no cycle has completed within maxAge, counting from start up before the first
+func (h *health) live(maxAge time.Duration) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	last := h.lastCycle
+	if last.IsZero() {
+		last = h.started
+	}
+	if maxAge > 0 && time.Since(last) > maxAge {
+		return fmt.Errorf("no cycle has completed since %s", last.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// rpo returns the outcome of the last RPO check, starting check in the background if no check has run since the
+// last cycle completed. So it runs at most once a cycle, and only while asked for, without holding up the caller.
+func (h *health) rpo(check func() error) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.rpoRunning && (!h.rpoChecked || h.lastCycle.After(h.rpoCycle)) {
+		h.rpoRunning, h.rpoChecked, h.rpoCycle = true, true, h.lastCycle
+		go func() {
+			err := check()
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			h.rpoErr, h.rpoRunning = err, false
+		}()
+	}
+	return h.rpoErr
+}
+
+// ready returns the error of probe, which checks AWS can be called, reusing its outcome for readyTTL
+func (h *health) ready(probe func() error) error {
+	h.probing.Lock()
+	defer h.probing.Unlock()
+	if h.probed.IsZero() || time.Since(h.probed) > readyTTL {
+		h.probeErr = probe()
+		h.probed = time.Now()
+	}
+	return h.probeErr
+}
+
+// probe checks the source region's RDS API can be called, with the credentials and role cfg uses, by listing a
+// single page of rds instances
+func probe(ctx context.Context, cfg *wiring.Config) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	SrcRDSSource, err := newSession(cfg, cfg.SourceRegion, cfg.SourceRoleARN)
+	if err == nil {
+		_, err = SrcRDSSource.DescribeDBInstancesWithContext(ctx, &rds.DescribeDBInstancesInput{MaxRecords: aws.Int64(20)})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to call AWS rds in the source region %s: %v", cfg.SourceRegion, err)
+	}
+	return nil
+}
+
+// handler serves a health check, 200 if check returns nil, otherwise 503 with the error
+func handler(check func() error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := check(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	}
+}
@@ -0,0 +1,112 @@
+package worker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealth(t *testing.T) {
+	t.Parallel()
+
+	h := newHealth()
+	live := handler(func() error { return h.live(time.Hour) })
+	probes := 0
+	var probeErr error
+	ready := handler(func() error {
+		return h.ready(func() error {
+			probes++
+			return probeErr
+		})
+	})
+
+	check := func(name string, hf http.HandlerFunc, want int) {
+		rec := httptest.NewRecorder()
+		hf(rec, httptest.NewRequest("GET", "/", nil))
+		if rec.Code != want {
+			t.Errorf("%v = %d, want %d: %s", name, rec.Code, want, rec.Body.String())
+		}
+	}
+
+	check("Health_live_at_start", live, http.StatusOK)
+	check("Health_ready", ready, http.StatusOK)
+
+	// The probe's outcome is reused until it is stale
+	probeErr = errors.New("no credentials")
+	check("Health_ready_cached", ready, http.StatusOK)
+	if probes != 1 {
+		t.Errorf("ready() probed %d times, want 1", probes)
+	}
+	h.probed = time.Now().Add(-time.Hour)
+	check("Health_aws_failing", ready, http.StatusServiceUnavailable)
+	probeErr = nil
+	h.probed = time.Now().Add(-time.Hour)
+	check("Health_aws_recovered", ready, http.StatusOK)
+
+	h.started = time.Now().Add(-2 * time.Hour)
+	check("Health_no_cycle", live, http.StatusServiceUnavailable)
+
+	h.cycled()
+	check("Health_cycled", live, http.StatusOK)
+
+	h.lastCycle = time.Now().Add(-2 * time.Hour)
+	check("Health_stuck", live, http.StatusServiceUnavailable)
+
+	if err := h.live(0); err != nil {
+		t.Errorf("live(0) = %v, want nil", err)
+	}
+}
+
+func TestHealthRPO(t *testing.T) {
+	t.Parallel()
+
+	h := newHealth()
+	checks := make(chan chan error, 1)
+	check := func() error {
+		res := make(chan error)
+		checks <- res
+		return <-res
+	}
+	// finish completes the check in progress with err, and waits for its outcome to be recorded
+	finish := func(err error) {
+		(<-checks) <- err
+		for {
+			h.mu.Lock()
+			running := h.rpoRunning
+			h.mu.Unlock()
+			if !running {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// The first call starts a check, without waiting for it
+	if err := h.rpo(check); err != nil {
+		t.Errorf("rpo() before any check = %v, want nil", err)
+	}
+	if err := h.rpo(check); err != nil {
+		t.Errorf("rpo() while checking = %v, want nil", err)
+	}
+	finish(errors.New("RPO breached by db-1 in us-west-2"))
+
+	// The outcome is kept until a cycle completes
+	if err := h.rpo(check); err == nil {
+		t.Error("rpo() after a breach = nil, want the breach")
+	}
+	select {
+	case <-checks:
+		t.Fatal("rpo() checked again without a cycle completing")
+	default:
+	}
+
+	time.Sleep(time.Millisecond)
+	h.cycled()
+	h.rpo(check)
+	finish(nil)
+	if err := h.rpo(check); err != nil {
+		t.Errorf("rpo() after the cycle = %v, want nil", err)
+	}
+}
@@ -0,0 +1,54 @@
+package worker
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/events"
+	"github.com/bluebenno/rds-snapshot-copier/internal/metrics"
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+// serve serves the HTTP endpoints on addr, until ctx is cancelled:
+// - /metrics: Prometheus metrics
+// - /healthz: fails if no cycle has completed within LiveCycles runs, of the config in use, or an RPO is breached
+// - /readyz:  fails if the source region's RDS API can not be called, probing it at most every readyTTL
+// - /events:  with cfg.EventWebhook, accepts RDS events, passing snapshots being created to onEvent
+func serve(ctx context.Context, logger *zap.Logger, reload *Reloader, addr string, onEvent func(*events.Event) bool) {
+	cfg := reload.Config()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/healthz", handler(func() error {
+		cfg := reload.Config()
+		if err := status.live(maxAge(cfg)); err != nil || cfg.RPO == 0 {
+			return err
+		}
+		return status.rpo(func() error { return rpoErr(ctx, logger, cfg) })
+	}))
+	mux.Handle("/readyz", handler(func() error { return status.ready(func() error { return probe(ctx, reload.Config()) }) }))
+	if cfg.EventWebhook {
+		mux.Handle("/events", webhook(onEvent))
+	}
+
+	srv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		sctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(sctx)
+	}()
+
+	logger.Info("Serving HTTP", zap.String("addr", addr))
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Error("Failed to serve HTTP", zap.String("addr", addr), zap.Error(err))
+	}
+}
+
+// maxAge is how long since the last cycle completed before the health check fails, 0 never fails
+func maxAge(cfg *wiring.Config) time.Duration {
+	return time.Duration(cfg.LiveCycles) * every(cfg)
+}
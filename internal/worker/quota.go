@@ -0,0 +1,128 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	"go.uber.org/zap"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/snapops"
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+// quotaPoll is how often a copy waiting for a slot in the target region recounts the copies in progress there
+var quotaPoll = 30 * time.Second
+
+// quota limits the copies in progress in a target region to MaxCopyInFlight, counting those started by anything
+// else, e.g. another tool or a previous run that crashed, as well as our own
+type quota struct {
+	mu    sync.Mutex
+	limit int
+	ours  int                                    // Copies we have started, and are waiting for
+	count func(ctx context.Context) (int, error) // Copies in progress in the target region, ours included
+	poll  time.Duration
+}
+
+// quotas are the quotas of each target region and account, shared by every cycle and event copying into it
+var quotas = struct {
+	sync.Mutex
+	m map[string]*quota
+}{m: make(map[string]*quota)}
+
+// regionQuota returns the quota of the target region of cfg, shared by every copy into it for the life of the
+// process. Its copies in progress are counted with srcRDSTarget, the latest session there.
+func regionQuota(logger *zap.Logger, cfg *wiring.Config, srcRDSTarget rdsiface.RDSAPI) *quota {
+	quotas.Lock()
+	defer quotas.Unlock()
+	key := cfg.TargetRegion + " " + cfg.TargetRoleARN
+	q, ok := quotas.m[key]
+	if !ok {
+		q = &quota{}
+		quotas.m[key] = q
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.limit, q.poll = cfg.MaxCopyInFlight, quotaPoll
+	q.count = func(ctx context.Context) (int, error) {
+		n, err := countCopying(ctx, cfg, srcRDSTarget)
+		if err != nil {
+			logger.Warn("Failed to count the snapshot copies in progress in the target region", zap.String("target_region", cfg.TargetRegion), zap.Error(err))
+		}
+		return n, err
+	}
+	return q
+}
+
+// countCopying counts the snapshots, of the types in scope, being copied into the target region
+func countCopying(ctx context.Context, cfg *wiring.Config, srcRDSTarget rdsiface.RDSAPI) (int, error) {
+	var n int
+	if cfg.Instances() {
+		s, err := snapops.ListCopying(ctx, srcRDSTarget)
+		if err != nil {
+			return 0, err
+		}
+		n += len(s)
+	}
+	if cfg.Clusters() {
+		s, err := snapops.ListCopyingCluster(ctx, srcRDSTarget)
+		if err != nil {
+			return 0, err
+		}
+		n += len(s)
+	}
+	return n, nil
+}
+
+// acquire waits for a slot for a copy, until ctx is done. If the copies in progress can not be counted, only our
+// own are. waited is called each time there is no slot, with the number of copies started by others.
+func (q *quota) acquire(ctx context.Context, waited func(others int)) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// Count outside the lock, as listing the copies may be slow or throttled
+		q.mu.Lock()
+		count, poll := q.count, q.poll
+		q.mu.Unlock()
+		n, err := count(ctx)
+		q.mu.Lock()
+		others := 0
+		if err == nil && n > q.ours {
+			others = n - q.ours
+		}
+		if q.ours+others < q.limit {
+			q.ours++
+			q.mu.Unlock()
+			return nil
+		}
+		q.mu.Unlock()
+
+		if waited != nil {
+			waited(others)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(poll):
+		}
+	}
+}
+
+// take takes a slot for a copy already in progress, regardless of the limit
+func (q *quota) take() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.ours++
+}
+
+// release frees the slot of a copy which has finished, or was never started
+func (q *quota) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.ours > 0 {
+		q.ours--
+	}
+}
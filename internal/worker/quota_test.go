@@ -0,0 +1,124 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+func TestQuota(t *testing.T) {
+	t.Parallel()
+
+	inProgress := 0
+	var countErr error
+	q := &quota{limit: 2, poll: time.Millisecond, count: func(ctx context.Context) (int, error) {
+		return inProgress, countErr
+	}}
+
+	// A copy started by something else takes one of the two slots
+	inProgress = 1
+	if err := q.acquire(context.Background(), nil); err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	inProgress = 2 // ours has now started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var waits, others int
+	if err := q.acquire(ctx, func(o int) { waits++; others = o }); err != context.DeadlineExceeded {
+		t.Errorf("acquire() with no slot error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if waits == 0 || others != 1 {
+		t.Errorf("acquire() waited %d times with %d others, want >0 and 1", waits, others)
+	}
+
+	// The other copy finishes
+	inProgress = 1
+	if err := q.acquire(context.Background(), nil); err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	if q.ours != 2 {
+		t.Errorf("ours = %d, want 2", q.ours)
+	}
+
+	// Only our own copies count when the region can not be listed
+	q.release()
+	countErr = errors.New("throttled")
+	inProgress = 5
+	if err := q.acquire(context.Background(), nil); err != nil {
+		t.Fatalf("acquire() when count fails error = %v", err)
+	}
+
+	q.release()
+	q.release()
+	q.release()
+	if q.ours != 0 {
+		t.Errorf("release() ours = %d, want 0", q.ours)
+	}
+}
+
+func TestQuotaCountUnlocked(t *testing.T) {
+	t.Parallel()
+
+	counting := make(chan struct{})
+	unblock := make(chan struct{})
+	q := &quota{limit: 2, poll: time.Millisecond, count: func(ctx context.Context) (int, error) {
+		close(counting)
+		<-unblock
+		return 0, nil
+	}}
+	q.take()
+
+	done := make(chan error)
+	go func() { done <- q.acquire(context.Background(), nil) }()
+	<-counting
+
+	// A slow count does not hold up a copy finishing
+	released := make(chan struct{})
+	go func() {
+		q.release()
+		close(released)
+	}()
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatal("release() blocked by a count in progress")
+	}
+
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	if q.ours != 1 {
+		t.Errorf("ours = %d, want 1", q.ours)
+	}
+}
+
+func TestRegionQuota(t *testing.T) {
+	t.Parallel()
+
+	logger := zap.NewNop()
+	cfg := &wiring.Config{TargetRegion: "sa-east-1", MaxCopyInFlight: 2}
+	q := regionQuota(logger, cfg, nil)
+	q.take()
+	defer q.release()
+
+	// Every copy into the region, e.g. from an event and a cycle, shares its slots
+	if got := regionQuota(logger, &wiring.Config{TargetRegion: "sa-east-1", MaxCopyInFlight: 3}, nil); got != q {
+		t.Errorf("regionQuota() = %p, want the shared %p", got, q)
+	}
+	if q.ours != 1 || q.limit != 3 {
+		t.Errorf("shared quota ours = %d, limit = %d, want 1 and 3", q.ours, q.limit)
+	}
+	if got := regionQuota(logger, &wiring.Config{TargetRegion: "sa-east-1", TargetRoleARN: "arn:aws:iam::210987654321:role/dr"}, nil); got == q {
+		t.Error("regionQuota() for another account shares its quota")
+	}
+	if got := regionQuota(logger, &wiring.Config{TargetRegion: "ca-central-1"}, nil); got == q {
+		t.Error("regionQuota() for another region shares its quota")
+	}
+}
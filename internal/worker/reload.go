@@ -0,0 +1,125 @@
+package worker
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+// reloadPoll is how often Watch checks the config file for changes
+var reloadPoll = 10 * time.Second
+
+// restart are the settings that are only read at startup, so a reloaded change to them has no effect
+var restart = map[string]bool{
+	"ConfigFile":   true,
+	"EventQueue":   true,
+	"EventWebhook": true,
+	"ListenAddr":   true,
+	"LogLevel":     true,
+	"Once":         true,
+}
+
+// Reloader reloads the config, e.g. on SIGHUP or when its file changes. A new config is validated, then applied
+// by Looper at the start of its next cycle; copies in progress carry on under the config they were started with.
+type Reloader struct {
+	logger *zap.Logger
+	load   func() (*wiring.Config, error)
+
+	mu      sync.Mutex
+	cfg     *wiring.Config // In use
+	pending *wiring.Config // Reloaded, to be used from the next cycle
+}
+
+// NewReloader returns a Reloader of cfg, which calls load to read the config afresh. With a nil load, the config
+// is never reloaded.
+func NewReloader(logger *zap.Logger, cfg *wiring.Config, load func() (*wiring.Config, error)) *Reloader {
+	return &Reloader{logger: logger, cfg: cfg, load: load}
+}
+
+// Config returns the config in use
+func (r *Reloader) Config() *wiring.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cfg
+}
+
+// Reload loads and validates the config, logging what changed. If it is invalid, it is logged and the config in
+// use is kept.
+func (r *Reloader) Reload() {
+	if r.load == nil {
+		return
+	}
+	cfg, err := r.load()
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		r.logger.Error("Failed to reload the config, keeping the current one", zap.Error(err))
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last := r.cfg
+	if r.pending != nil {
+		last = r.pending
+	}
+	changes := last.Diff(cfg)
+	if len(changes) == 0 {
+		r.logger.Info("Reloaded the config, it is unchanged")
+		return
+	}
+	r.pending = cfg
+
+	diff := make([]string, len(changes))
+	for i, c := range changes {
+		diff[i] = c.String()
+		if restart[c.Field] {
+			r.logger.Warn("Reloaded config setting only takes effect on restart", zap.String("setting", c.Field))
+		}
+	}
+	r.logger.Info("Reloaded the config, it applies from the next cycle", zap.Strings("changes", diff))
+}
+
+// apply makes the reloaded config, if any, the one in use and returns it
+func (r *Reloader) apply() *wiring.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending != nil {
+		r.cfg, r.pending = r.pending, nil
+		r.logger.Info("Applying the reloaded config")
+	}
+	return r.cfg
+}
+
+// Watch reloads the config whenever filename is modified, until ctx is cancelled
+func (r *Reloader) Watch(ctx context.Context, filename string) {
+	poll := reloadPoll
+	last, _ := os.Stat(filename)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(poll):
+		}
+		fi, err := os.Stat(filename)
+		if err != nil {
+			if last != nil {
+				r.logger.Warn("Failed to check the config file for changes", zap.String("config", filename), zap.Error(err))
+			}
+			last = nil
+			continue
+		}
+		if last != nil && fi.ModTime().Equal(last.ModTime()) && fi.Size() == last.Size() {
+			continue
+		}
+		last = fi
+		r.logger.Info("Config file changed, reloading", zap.String("config", filename))
+		r.Reload()
+	}
+}
@@ -0,0 +1,123 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+func TestReloader(t *testing.T) {
+	t.Parallel()
+
+	valid := func(tag string) *wiring.Config {
+		return &wiring.Config{SourceRegion: "ap-southeast-2", TargetRegion: "us-west-2", Tag: tag, MaxCopyInFlight: 1}
+	}
+
+	tests := []struct {
+		name    string
+		load    func() (*wiring.Config, error)
+		wantTag string
+	}{
+		{name: "Reload_applied", load: func() (*wiring.Config, error) { return valid("backup"), nil }, wantTag: "backup"},
+		{name: "Reload_unchanged", load: func() (*wiring.Config, error) { return valid("copy"), nil }, wantTag: "copy"},
+		{name: "Reload_invalid", load: func() (*wiring.Config, error) { return valid(""), nil }, wantTag: "copy"},
+		{name: "Reload_error", load: func() (*wiring.Config, error) { return nil, errors.New("bad file") }, wantTag: "copy"},
+		{name: "Reload_nil_load", wantTag: "copy"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := valid("copy")
+			r := NewReloader(zap.NewNop(), cfg, tt.load)
+			r.Reload()
+			if got := r.Config(); got != cfg {
+				t.Errorf("Config() = %v, want the config in use until the next cycle", got.Tag)
+			}
+			if got := r.apply().Tag; got != tt.wantTag {
+				t.Errorf("apply().Tag = %v, want %v", got, tt.wantTag)
+			}
+			if got := r.Config().Tag; got != tt.wantTag {
+				t.Errorf("Config().Tag = %v, want %v", got, tt.wantTag)
+			}
+		})
+	}
+}
+
+func TestReloaderWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(filename, []byte("defaults: {}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(p time.Duration) { reloadPoll = p }(reloadPoll)
+	reloadPoll = 10 * time.Millisecond
+
+	var mu sync.Mutex
+	loads := 0
+	r := NewReloader(zap.NewNop(), &wiring.Config{}, func() (*wiring.Config, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		loads++
+		return nil, errors.New("not needed")
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx, filename)
+
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return loads
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := count(); got != 0 {
+		t.Errorf("loads before a change = %v, want 0", got)
+	}
+
+	if err := ioutil.WriteFile(filename, []byte("defaults: {tag: [backup]}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100 && count() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := count(); got != 1 {
+		t.Errorf("loads after a change = %v, want 1", got)
+	}
+}
+
+func TestReloaderMaxAge(t *testing.T) {
+	t.Parallel()
+
+	cfg := &wiring.Config{SourceRegion: "ap-southeast-2", TargetRegion: "us-west-2", Tag: "copy", MaxCopyInFlight: 1, RunEvery: 60, LiveCycles: 3}
+	r := NewReloader(zap.NewNop(), cfg, func() (*wiring.Config, error) {
+		c := *cfg
+		c.RunEvery = 240
+		return &c, nil
+	})
+	if got, want := maxAge(r.Config()), 3*time.Hour; got != want {
+		t.Errorf("maxAge() = %v, want %v", got, want)
+	}
+
+	// The health check follows a reloaded RunEvery once it is applied
+	r.Reload()
+	r.apply()
+	if got, want := maxAge(r.Config()), 12*time.Hour; got != want {
+		t.Errorf("maxAge() after reload = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,189 @@
+package worker
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Outcome is what happened to a single snapshot, or rds, in a run
+type Outcome struct {
+	Source           string    `json:"source"`             // The rds instance or Aurora cluster
+	Snapshot         string    `json:"snapshot,omitempty"` // The source snapshot
+	TargetRegion     string    `json:"target_region,omitempty"`
+	Result           string    `json:"result"`           // A copyState, or "skipped"
+	Reason           string    `json:"reason,omitempty"` // Why it was skipped
+	Error            string    `json:"error,omitempty"`
+	Start            time.Time `json:"start,omitempty"`
+	Finish           time.Time `json:"finish,omitempty"`
+	Seconds          float64   `json:"seconds,omitempty"`
+	AllocatedStorage int64     `json:"allocated_storage_gib,omitempty"`
+	PercentProgress  int64     `json:"percent_progress,omitempty"`
+}
+
+// RunReport is the result of a single run (cycle) of the copier. It is safe for concurrent use, and a nil
+// *RunReport discards everything.
+type RunReport struct {
+	SourceRegion string         `json:"source_region"`
+	Start        time.Time      `json:"start"`
+	Finish       time.Time      `json:"finish"`
+	Outcomes     []Outcome      `json:"outcomes"`
+	Deleted      map[string]int `json:"deleted,omitempty"` // Snapshots housekept, per target region
+	Errors       []string       `json:"errors,omitempty"`  // Errors not specific to a snapshot
+
+	mu sync.Mutex
+}
+
+// NewRunReport starts a report
+func NewRunReport(sourceRegion string) *RunReport {
+	return &RunReport{SourceRegion: sourceRegion, Start: time.Now()}
+}
+
+// Add records the outcome of a snapshot
+func (r *RunReport) Add(o Outcome) {
+	if r == nil {
+		return
+	}
+	if !o.Start.IsZero() && !o.Finish.IsZero() {
+		o.Seconds = o.Finish.Sub(o.Start).Seconds()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Outcomes = append(r.Outcomes, o)
+}
+
+// Skip records that a snapshot, or a whole rds if snapshot is "", was not copied and why
+func (r *RunReport) Skip(source, snapshot, region, reason string) {
+	r.Add(Outcome{Source: source, Snapshot: snapshot, TargetRegion: region, Result: "skipped", Reason: reason})
+}
+
+// Error records an error not specific to a snapshot
+func (r *RunReport) Error(err error) {
+	if r == nil || err == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Errors = append(r.Errors, err.Error())
+}
+
+// AddDeleted records the number of snapshots housekept from a target region
+func (r *RunReport) AddDeleted(region string, n int) {
+	if r == nil || n == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.Deleted == nil {
+		r.Deleted = make(map[string]int)
+	}
+	r.Deleted[region] += n
+}
+
+// Finished marks the end of the run
+func (r *RunReport) Finished() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Finish = time.Now()
+	sort.SliceStable(r.Outcomes, func(i, j int) bool {
+		if r.Outcomes[i].Source != r.Outcomes[j].Source {
+			return r.Outcomes[i].Source < r.Outcomes[j].Source
+		}
+		return r.Outcomes[i].TargetRegion < r.Outcomes[j].TargetRegion
+	})
+}
+
+// Counts returns the number of outcomes of each result, e.g. "available", "failed", "skipped"
+func (r *RunReport) Counts() map[string]int {
+	counts := make(map[string]int)
+	if r == nil {
+		return counts
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, o := range r.Outcomes {
+		counts[o.Result]++
+	}
+	return counts
+}
+
+// Log logs the report as a single summary line
+func (r *RunReport) Log(logger *zap.Logger) {
+	if r == nil {
+		return
+	}
+	counts := r.Counts()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var storage int64
+	var deleted int
+	for _, o := range r.Outcomes {
+		if o.Result == string(stateAvailable) {
+			storage += o.AllocatedStorage
+		}
+	}
+	for _, n := range r.Deleted {
+		deleted += n
+	}
+
+	fields := []zap.Field{
+		zap.String("source_region", r.SourceRegion),
+		zap.Duration("duration", r.Finish.Sub(r.Start)),
+		zap.Int64("copied_gib", storage),
+		zap.Int("deleted", deleted),
+		zap.Strings("errors", r.Errors),
+	}
+	for _, k := range []string{string(stateAvailable), string(stateFailed), string(stateTimedOut), string(stateVanished), string(stateAbandoned), string(stateHandedOff), "skipped"} {
+		fields = append(fields, zap.Int(k, counts[k]))
+	}
+	logger.Info("Run complete", fields...)
+}
+
+// writeMu serialises writing reports, as those of cycles and events may finish together
+var writeMu sync.Mutex
+
+// Write writes the report as JSON to path, or stdout if path is "-". A file is replaced whole, so a reader never
+// sees one half written.
+func (r *RunReport) Write(path string) error {
+	r.mu.Lock()
+	b, err := json.MarshalIndent(r, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	if path == "-" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,85 @@
+package worker
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRunReport(t *testing.T) {
+	t.Parallel()
+
+	start := time.Now()
+	r := NewRunReport("ap-southeast-2")
+	r.Add(Outcome{Source: "b", Snapshot: "b-1", TargetRegion: "us-west-2", Result: string(stateAvailable), Start: start, Finish: start.Add(time.Minute), AllocatedStorage: 20})
+	r.Add(Outcome{Source: "a", Snapshot: "a-1", TargetRegion: "us-west-2", Result: string(stateFailed), Error: "boom"})
+	r.Skip("c", "", "", "invalid copy policy")
+	r.Error(errors.New("us-east-1: no session"))
+	r.Error(nil)
+	r.AddDeleted("us-west-2", 2)
+	r.AddDeleted("us-west-2", 1)
+	r.AddDeleted("eu-west-1", 0)
+	r.Finished()
+
+	want := map[string]int{"available": 1, "failed": 1, "skipped": 1}
+	if got := r.Counts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Counts() = %v, want %v", got, want)
+	}
+	if r.Outcomes[0].Source != "a" || r.Outcomes[2].Source != "c" {
+		t.Errorf("Finished() did not order the outcomes by source: %+v", r.Outcomes)
+	}
+	if r.Outcomes[1].Seconds != 60 {
+		t.Errorf("Add() seconds = %v, want 60", r.Outcomes[1].Seconds)
+	}
+	if !reflect.DeepEqual(r.Deleted, map[string]int{"us-west-2": 3}) {
+		t.Errorf("AddDeleted() = %v", r.Deleted)
+	}
+	if len(r.Errors) != 1 {
+		t.Errorf("Error() = %v, want 1 error", r.Errors)
+	}
+
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "report.json")
+	if err := r.Write(path); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got RunReport
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Write() wrote invalid JSON: %v", err)
+	}
+	if got.SourceRegion != "ap-southeast-2" || len(got.Outcomes) != 3 || got.Outcomes[0].Error != "boom" {
+		t.Errorf("Write() = %s", b)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("Write() left %d files, want only the report", len(files))
+	}
+}
+
+func TestRunReportNil(t *testing.T) {
+	t.Parallel()
+
+	// A nil report discards everything
+	var r *RunReport
+	r.Add(Outcome{Source: "a"})
+	r.Skip("a", "", "", "reason")
+	r.Error(errors.New("boom"))
+	r.AddDeleted("us-west-2", 1)
+	r.Finished()
+	if got := r.Counts(); len(got) != 0 {
+		t.Errorf("Counts() = %v, want none", got)
+	}
+}
@@ -0,0 +1,242 @@
+package worker
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	"go.uber.org/zap"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/rdsops"
+	"github.com/bluebenno/rds-snapshot-copier/internal/snapops"
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+// resumeWindow is the least time since a snapshot was taken that a finished copy of it is still checked for this
+// tool's tags, e.g. one handed off which finished between runs. Older finished copies are taken to be done with,
+// sparing a ListTagsForResource call for each copy kept
+const resumeWindow = 7 * 24 * time.Hour
+
+// resumable returns a copyjob for each copy of an in scope rds instance or Aurora cluster that this tool started in
+// region but has not finished with, e.g. as it was handed off, or the tool restarted mid copy. These are the copies
+// still tagged with snapops.SourceTag, whatever state they have since reached, bar those settled long ago. Those still
+// in progress are polled until they finish, counting against MaxCopyInFlight; the rest are reported, and cleaned up,
+// as they stand.
+// For another account, so are the manual copies of automated snapshots made in the source region to share them, see
+// shareable, whose copy to region has yet to start.
+func resumable(ctx context.Context, logger *zap.Logger, region string, srcRDSSource, srcRDSTarget rdsiface.RDSAPI, isr []rdsops.InScope, isc []rdsops.InScopeCluster) ([]copyjob, error) {
+	var jobs []copyjob
+	now := time.Now()
+
+	instances := make(map[string]rdsops.InScope)
+	for _, is := range isr {
+		if contains(is.Config.TargetRegions(), region) {
+			instances[*is.Instance.DBInstanceIdentifier] = is
+		}
+	}
+	if len(instances) > 0 {
+		copied, err := snapops.ListCopied(ctx, srcRDSTarget)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range copied {
+			is, ok := instances[aws.StringValue(s.DBInstanceIdentifier)]
+			if !ok {
+				continue
+			}
+			cfg := is.Config.ForRegion(region)
+			if !snapops.IsCopy(cfg, aws.StringValue(s.DBSnapshotIdentifier)) || settled(cfg, aws.StringValue(s.Status), s.SnapshotCreateTime, now) {
+				continue
+			}
+			tags, err := snapops.Tags(ctx, srcRDSTarget, aws.StringValue(s.DBSnapshotArn))
+			if err != nil {
+				return nil, err
+			}
+			source := tags[snapops.SourceTag]
+			if source == "" {
+				continue // Finished with
+			}
+			jobs = append(jobs, copyjob{cfg: cfg, target: *s.DBSnapshotIdentifier, targetARN: *s.DBSnapshotArn, share: tags[snapops.ShareTag], snapshot: &rds.DBSnapshot{
+				DBInstanceIdentifier: s.DBInstanceIdentifier,
+				DBSnapshotIdentifier: aws.String(source),
+				AllocatedStorage:     s.AllocatedStorage,
+				SnapshotType:         aws.String(sourceType(source)),
+			}})
+		}
+	}
+
+	clusters := make(map[string]rdsops.InScopeCluster)
+	for _, is := range isc {
+		if contains(is.Config.TargetRegions(), region) {
+			clusters[*is.Cluster.DBClusterIdentifier] = is
+		}
+	}
+	if len(clusters) > 0 {
+		copied, err := snapops.ListCopiedCluster(ctx, srcRDSTarget)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range copied {
+			is, ok := clusters[aws.StringValue(s.DBClusterIdentifier)]
+			if !ok {
+				continue
+			}
+			cfg := is.Config.ForRegion(region)
+			if !snapops.IsCopy(cfg, aws.StringValue(s.DBClusterSnapshotIdentifier)) || settled(cfg, aws.StringValue(s.Status), s.SnapshotCreateTime, now) {
+				continue
+			}
+			tags, err := snapops.Tags(ctx, srcRDSTarget, aws.StringValue(s.DBClusterSnapshotArn))
+			if err != nil {
+				return nil, err
+			}
+			source := tags[snapops.SourceTag]
+			if source == "" {
+				continue // Finished with
+			}
+			jobs = append(jobs, copyjob{cfg: cfg, target: *s.DBClusterSnapshotIdentifier, targetARN: *s.DBClusterSnapshotArn, share: tags[snapops.ShareTag], cluster: &rds.DBClusterSnapshot{
+				DBClusterIdentifier:         s.DBClusterIdentifier,
+				DBClusterSnapshotIdentifier: aws.String(source),
+				AllocatedStorage:            s.AllocatedStorage,
+				SnapshotType:                aws.String(sourceType(source)),
+			}})
+		}
+	}
+
+	shares, err := resumableShares(ctx, region, srcRDSSource, instances, clusters, jobs)
+	if err != nil {
+		return nil, err
+	}
+	jobs = append(jobs, shares...)
+
+	for _, j := range jobs {
+		logger.Info("Found snapshot copy started by an earlier run", zap.String("target_region", region), zap.String("rds", j.source()), zap.String("target_snapshot", j.target),
+			zap.String("share_snapshot", j.share))
+	}
+	return jobs, nil
+}
+
+// resumableShares returns a copyjob for each manual copy of an automated snapshot of instances or clusters that this
+// tool made in the source region to share with the account copying it to region, and not yet finished with. Those
+// whose copy to region is already among resumed are left out.
+func resumableShares(ctx context.Context, region string, srcRDSSource rdsiface.RDSAPI, instances map[string]rdsops.InScope, clusters map[string]rdsops.InScopeCluster, resumed []copyjob) ([]copyjob, error) {
+	var jobs []copyjob
+	seen := make(map[string]bool)
+	for _, j := range resumed {
+		seen[j.key()] = true
+	}
+
+	var listInstances, listClusters bool
+	for _, is := range instances {
+		listInstances = listInstances || is.Config.ForRegion(region).CrossAccount() != ""
+	}
+	for _, is := range clusters {
+		listClusters = listClusters || is.Config.ForRegion(region).CrossAccount() != ""
+	}
+
+	if listInstances {
+		shares, err := snapops.ListShares(ctx, srcRDSSource, region)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range shares {
+			is, ok := instances[aws.StringValue(s.DBInstanceIdentifier)]
+			if !ok || is.Config.ForRegion(region).CrossAccount() == "" {
+				continue
+			}
+			tags, err := snapops.Tags(ctx, srcRDSSource, aws.StringValue(s.DBSnapshotArn))
+			if err != nil {
+				return nil, err
+			}
+			source := tags[snapops.SourceTag]
+			if source == "" {
+				continue // Made by an older version of this tool, which waited for it
+			}
+			j := copyjob{cfg: is.Config.ForRegion(region), share: *s.DBSnapshotIdentifier, snapshot: &rds.DBSnapshot{
+				DBInstanceIdentifier: s.DBInstanceIdentifier,
+				DBSnapshotIdentifier: aws.String(source),
+				DBSnapshotArn:        aws.String(strings.TrimSuffix(aws.StringValue(s.DBSnapshotArn), *s.DBSnapshotIdentifier) + source),
+				AllocatedStorage:     s.AllocatedStorage,
+				SnapshotType:         aws.String("automated"),
+			}}
+			if !seen[j.key()] {
+				jobs = append(jobs, j)
+			}
+		}
+	}
+
+	if listClusters {
+		shares, err := snapops.ListSharesCluster(ctx, srcRDSSource, region)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range shares {
+			is, ok := clusters[aws.StringValue(s.DBClusterIdentifier)]
+			if !ok || is.Config.ForRegion(region).CrossAccount() == "" {
+				continue
+			}
+			tags, err := snapops.Tags(ctx, srcRDSSource, aws.StringValue(s.DBClusterSnapshotArn))
+			if err != nil {
+				return nil, err
+			}
+			source := tags[snapops.SourceTag]
+			if source == "" {
+				continue // Made by an older version of this tool, which waited for it
+			}
+			j := copyjob{cfg: is.Config.ForRegion(region), share: *s.DBClusterSnapshotIdentifier, cluster: &rds.DBClusterSnapshot{
+				DBClusterIdentifier:         s.DBClusterIdentifier,
+				DBClusterSnapshotIdentifier: aws.String(source),
+				DBClusterSnapshotArn:        aws.String(strings.TrimSuffix(aws.StringValue(s.DBClusterSnapshotArn), *s.DBClusterSnapshotIdentifier) + source),
+				AllocatedStorage:            s.AllocatedStorage,
+				SnapshotType:                aws.String("automated"),
+			}}
+			if !seen[j.key()] {
+				jobs = append(jobs, j)
+			}
+		}
+	}
+	return jobs, nil
+}
+
+// unresumed returns the jobs in queue whose copy is not already among resumed, e.g. as an earlier run left the manual
+// copy of its automated snapshot to share in progress
+func unresumed(queue, resumed []copyjob) []copyjob {
+	seen := make(map[string]bool)
+	for _, j := range resumed {
+		seen[j.key()] = true
+	}
+	var res []copyjob
+	for _, j := range queue {
+		if !seen[j.key()] {
+			res = append(res, j)
+		}
+	}
+	return res
+}
+
+// settled reports if a copy with status, of a snapshot taken at created, finished so long ago that it is not checked
+// for this tool's tags. That is one available, of a snapshot taken before the longest of resumeWindow, CopyTimeout
+// and Lookback.
+func settled(cfg *wiring.Config, status string, created *time.Time, now time.Time) bool {
+	if status != "available" || created == nil {
+		return false
+	}
+	window := resumeWindow
+	if cfg.CopyTimeout > window {
+		window = cfg.CopyTimeout
+	}
+	if cfg.Lookback > window {
+		window = cfg.Lookback
+	}
+	return created.Before(now.Add(-window))
+}
+
+// sourceType is the type of a source snapshot, from its identifier; AWS names automated snapshots "rds:..."
+func sourceType(id string) string {
+	if strings.HasPrefix(id, "rds:") {
+		return "automated"
+	}
+	return "manual"
+}
@@ -0,0 +1,148 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	"go.uber.org/zap"
+
+	"github.com/bluebenno/rds-snapshot-copier/internal/rdsops"
+	"github.com/bluebenno/rds-snapshot-copier/internal/snapops"
+	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
+)
+
+type mockRDSClient struct {
+	rdsiface.RDSAPI
+	snapshots        []*rds.DBSnapshot
+	clusterSnapshots []*rds.DBClusterSnapshot
+	tags             map[string]map[string]string // By ARN
+	tagged           []string                     // The ARNs whose tags were listed
+}
+
+// Mock DescribeDBSnapshotsPagesWithContext
+func (m *mockRDSClient) DescribeDBSnapshotsPagesWithContext(ctx aws.Context, i *rds.DescribeDBSnapshotsInput, fn func(*rds.DescribeDBSnapshotsOutput, bool) bool, opts ...request.Option) error {
+	fn(&rds.DescribeDBSnapshotsOutput{DBSnapshots: m.snapshots}, true)
+	return nil
+}
+
+// Mock DescribeDBClusterSnapshotsWithContext
+func (m *mockRDSClient) DescribeDBClusterSnapshotsWithContext(ctx aws.Context, i *rds.DescribeDBClusterSnapshotsInput, opts ...request.Option) (*rds.DescribeDBClusterSnapshotsOutput, error) {
+	return &rds.DescribeDBClusterSnapshotsOutput{DBClusterSnapshots: m.clusterSnapshots}, nil
+}
+
+// Mock ListTagsForResourceWithContext
+func (m *mockRDSClient) ListTagsForResourceWithContext(ctx aws.Context, i *rds.ListTagsForResourceInput, opts ...request.Option) (*rds.ListTagsForResourceOutput, error) {
+	m.tagged = append(m.tagged, aws.StringValue(i.ResourceName))
+	out := &rds.ListTagsForResourceOutput{}
+	for k, v := range m.tags[aws.StringValue(i.ResourceName)] {
+		out.TagList = append(out.TagList, &rds.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return out, nil
+}
+
+func TestResumable(t *testing.T) {
+	t.Parallel()
+
+	cfg := &wiring.Config{SourceRegion: "ap-southeast-2", TargetRegion: "us-west-2"}
+	isr := []rdsops.InScope{
+		{Instance: &rds.DBInstance{DBInstanceIdentifier: aws.String("db-1")}, Config: cfg},
+		{Instance: &rds.DBInstance{DBInstanceIdentifier: aws.String("db-2")}, Config: &wiring.Config{SourceRegion: "ap-southeast-2", TargetRegion: "eu-west-1"}},
+	}
+	isc := []rdsops.InScopeCluster{
+		{Cluster: &rds.DBCluster{DBClusterIdentifier: aws.String("aurora-1")}, Config: cfg},
+	}
+	copied := func(instance, id, status string) *rds.DBSnapshot {
+		return &rds.DBSnapshot{DBInstanceIdentifier: aws.String(instance), DBSnapshotIdentifier: aws.String(id), DBSnapshotArn: aws.String("arn:" + id),
+			SourceRegion: aws.String("ap-southeast-2"), Status: aws.String(status), AllocatedStorage: aws.Int64(20)}
+	}
+	clusterCopied := func(id, status string) *rds.DBClusterSnapshot {
+		return &rds.DBClusterSnapshot{DBClusterIdentifier: aws.String("aurora-1"), DBClusterSnapshotIdentifier: aws.String(id), DBClusterSnapshotArn: aws.String("arn:" + id),
+			SourceDBClusterSnapshotArn: aws.String("arn:aws:rds:ap-southeast-2:123456789012:cluster-snapshot:" + id), Status: aws.String(status)}
+	}
+	source := func(id string) map[string]string { return map[string]string{snapops.SourceTag: id} }
+	settledCopy := copied("db-1", "db-1-2019-02-01-cf-ap-southeast-2", "available")
+	settledCopy.SnapshotCreateTime = aws.Time(time.Now().Add(-resumeWindow - time.Hour))
+	mockSvc := &mockRDSClient{
+		snapshots: []*rds.DBSnapshot{
+			copied("db-1", "db-1-2019-03-30-cf-ap-southeast-2", "copying"),
+			copied("db-1", "db-1-2019-03-29-cf-ap-southeast-2", "available"), // Handed off, and since finished
+			copied("db-1", "db-1-2019-03-28-cf-ap-southeast-2", "available"), // Finished with
+			settledCopy, // Left tagged, but finished long ago
+			copied("db-1", "db-1-by-another-tool", "copying"),
+			copied("db-2", "db-2-2019-03-30-cf-ap-southeast-2", "copying"), // Not copied to us-west-2
+			copied("db-3", "db-3-2019-03-30-cf-ap-southeast-2", "copying"), // Not in scope
+		},
+		clusterSnapshots: []*rds.DBClusterSnapshot{
+			clusterCopied("aurora-1-2019-03-30-cf-ap-southeast-2", "failed"),
+			clusterCopied("aurora-1-2019-03-29-cf-ap-southeast-2", "available"),
+		},
+		tags: map[string]map[string]string{
+			"arn:db-1-2019-03-30-cf-ap-southeast-2":     source("rds:db-1-2019-03-30"),
+			"arn:db-1-2019-03-29-cf-ap-southeast-2":     {snapops.SourceTag: "rds:db-1-2019-03-29", snapops.ShareTag: "db-1-2019-03-29-share-us-west-2"},
+			"arn:db-1-2019-03-28-cf-ap-southeast-2":     {"team": "data"},
+			"arn:db-1-2019-02-01-cf-ap-southeast-2":     source("rds:db-1-2019-02-01"),
+			"arn:db-1-by-another-tool":                  source("rds:db-1-2019-03-30"),
+			"arn:db-2-2019-03-30-cf-ap-southeast-2":     source("rds:db-2-2019-03-30"),
+			"arn:db-3-2019-03-30-cf-ap-southeast-2":     source("rds:db-3-2019-03-30"),
+			"arn:aurora-1-2019-03-30-cf-ap-southeast-2": source("aurora-1-2019-03-30"),
+		},
+	}
+
+	jobs, err := resumable(context.Background(), zap.NewNop(), "us-west-2", mockSvc, mockSvc, isr, isc)
+	if err != nil {
+		t.Fatalf("resumable() error = %v", err)
+	}
+	if len(jobs) != 3 {
+		t.Fatalf("resumable() = %d jobs, want 3", len(jobs))
+	}
+	if jobs[0].target != "db-1-2019-03-30-cf-ap-southeast-2" || jobs[0].id() != "rds:db-1-2019-03-30" || jobs[0].source() != "db-1" || jobs[0].storage() != 20 ||
+		!jobs[0].automated() || jobs[0].share != "" || jobs[0].targetARN != "arn:db-1-2019-03-30-cf-ap-southeast-2" {
+		t.Errorf("resumable() instance job = %+v", jobs[0])
+	}
+	if jobs[1].target != "db-1-2019-03-29-cf-ap-southeast-2" || jobs[1].id() != "rds:db-1-2019-03-29" || jobs[1].share != "db-1-2019-03-29-share-us-west-2" {
+		t.Errorf("resumable() handed off instance job = %+v", jobs[1])
+	}
+	if jobs[2].target != "aurora-1-2019-03-30-cf-ap-southeast-2" || jobs[2].id() != "aurora-1-2019-03-30" || jobs[2].source() != "aurora-1" || jobs[2].automated() {
+		t.Errorf("resumable() cluster job = %+v", jobs[2])
+	}
+	for _, arn := range mockSvc.tagged {
+		if arn == *settledCopy.DBSnapshotArn {
+			t.Errorf("resumable() listed the tags of %s, settled long ago", arn)
+		}
+	}
+}
+
+func TestSettled(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	ago := func(d time.Duration) *time.Time { return aws.Time(now.Add(-d)) }
+	tests := []struct {
+		name    string
+		cfg     wiring.Config
+		status  string
+		created *time.Time
+		want    bool
+	}{
+		{"Settled_old_available", wiring.Config{}, "available", ago(resumeWindow + time.Hour), true},
+		{"Settled_recent_available", wiring.Config{}, "available", ago(time.Hour), false},
+		{"Settled_old_copying", wiring.Config{}, "copying", ago(resumeWindow + time.Hour), false},
+		{"Settled_old_failed", wiring.Config{}, "failed", ago(resumeWindow + time.Hour), false},
+		{"Settled_no_create_time", wiring.Config{}, "available", nil, false},
+		{"Settled_within_lookback", wiring.Config{Lookback: 2 * resumeWindow}, "available", ago(resumeWindow + time.Hour), false},
+		{"Settled_within_copytimeout", wiring.Config{CopyTimeout: 2 * resumeWindow}, "available", ago(resumeWindow + time.Hour), false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := settled(&tt.cfg, tt.status, tt.created, now); got != tt.want {
+				t.Errorf("settled() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package worker
+
+import "strings"
+
+// copyState is the state of a single snapshot copy
+type copyState string
+
+const (
+	stateRequested copyState = "requested"  // CopyDBSnapshot succeeded, the target snapshot has not yet been seen
+	stateCopying   copyState = "copying"    // The target snapshot exists and is being copied
+	stateAvailable copyState = "available"  // The copy completed
+	stateFailed    copyState = "failed"     // AWS failed the copy
+	stateVanished  copyState = "vanished"   // The target snapshot was deleted, or never appeared
+	stateTimedOut  copyState = "timed_out"  // The copy did not complete within CopyTimeout
+	stateAbandoned copyState = "abandoned"  // Stopped waiting on shutdown, the copy carries on in AWS
+	stateHandedOff copyState = "handed_off" // With NoWait, not waited for; the next run checks on the copy
+)
+
+// maxMisses is how many polls a requested copy may not be found for, before it is considered vanished.
+// Describe may briefly not find a copy which has just been requested.
+const maxMisses = 3
+
+// terminal reports if the copy has finished, one way or another
+func (s copyState) terminal() bool {
+	switch s {
+	case stateRequested, stateCopying:
+		return false
+	}
+	return true
+}
+
+// copyTracker follows a single copy through its states, from the target snapshot's status on each poll
+type copyTracker struct {
+	state    copyState
+	progress int64 // PercentProgress of the copy
+	misses   int   // Consecutive polls the target snapshot was not found
+}
+
+// newCopyTracker tracks a copy that has just been requested
+func newCopyTracker() *copyTracker {
+	return &copyTracker{state: stateRequested}
+}
+
+// observe moves the copy to its next state, given the status and PercentProgress of the target snapshot.
+// status is "" if the target snapshot was not found. A terminal state never changes.
+func (t *copyTracker) observe(status string, progress int64) copyState {
+	if t.state.terminal() {
+		return t.state
+	}
+
+	if status == "" {
+		t.misses++
+		if t.state == stateCopying || t.misses >= maxMisses {
+			t.state = stateVanished
+		}
+		return t.state
+	}
+	t.misses = 0
+
+	switch {
+	case status == "available":
+		t.state = stateAvailable
+		t.progress = 100
+	case status == "failed" || strings.HasPrefix(status, "incompatible"):
+		t.state = stateFailed
+	case status == "deleting" || status == "deleted":
+		t.state = stateVanished
+	default: // pending, creating, copying
+		t.state = stateCopying
+		t.progress = progress
+	}
+	return t.state
+}
+
+// end moves a copy that has not finished to a terminal state, e.g. stateTimedOut
+func (t *copyTracker) end(s copyState) copyState {
+	if !t.state.terminal() {
+		t.state = s
+	}
+	return t.state
+}
@@ -0,0 +1,114 @@
+package worker
+
+import (
+	"testing"
+)
+
+func TestCopyTracker(t *testing.T) {
+	t.Parallel()
+
+	type poll struct {
+		status   string
+		progress int64
+	}
+	tests := []struct {
+		name         string
+		polls        []poll
+		want         copyState
+		wantProgress int64
+	}{
+		{
+			name:         "CopyTracker_available",
+			polls:        []poll{{"pending", 0}, {"copying", 40}, {"available", 0}},
+			want:         stateAvailable,
+			wantProgress: 100,
+		},
+		{
+			name:         "CopyTracker_copying",
+			polls:        []poll{{"creating", 0}, {"copying", 70}},
+			want:         stateCopying,
+			wantProgress: 70,
+		},
+		{
+			name:         "CopyTracker_failed",
+			polls:        []poll{{"copying", 20}, {"failed", 0}},
+			want:         stateFailed,
+			wantProgress: 20,
+		},
+		{
+			name:  "CopyTracker_incompatible",
+			polls: []poll{{"incompatible-parameters", 0}},
+			want:  stateFailed,
+		},
+		{
+			name:  "CopyTracker_not_yet_seen",
+			polls: []poll{{"", 0}, {"", 0}},
+			want:  stateRequested,
+		},
+		{
+			name:         "CopyTracker_seen_after_miss",
+			polls:        []poll{{"", 0}, {"copying", 5}},
+			want:         stateCopying,
+			wantProgress: 5,
+		},
+		{
+			name:  "CopyTracker_never_appeared",
+			polls: []poll{{"", 0}, {"", 0}, {"", 0}},
+			want:  stateVanished,
+		},
+		{
+			name:         "CopyTracker_deleted_while_copying",
+			polls:        []poll{{"copying", 10}, {"", 0}},
+			want:         stateVanished,
+			wantProgress: 10,
+		},
+		{
+			name:         "CopyTracker_deleting",
+			polls:        []poll{{"copying", 10}, {"deleting", 0}},
+			want:         stateVanished,
+			wantProgress: 10,
+		},
+		{
+			name:         "CopyTracker_terminal_is_final",
+			polls:        []poll{{"available", 0}, {"", 0}, {"copying", 10}},
+			want:         stateAvailable,
+			wantProgress: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newCopyTracker()
+			for _, p := range tt.polls {
+				tracker.observe(p.status, p.progress)
+			}
+
+			if tracker.state != tt.want || tracker.progress != tt.wantProgress {
+				t.Errorf("%v = %v at %d%%, want %v at %d%%", tt.name, tracker.state, tracker.progress, tt.want, tt.wantProgress)
+			}
+		})
+	}
+}
+
+func TestCopyTrackerEnd(t *testing.T) {
+	t.Parallel()
+
+	tracker := newCopyTracker()
+	tracker.observe("copying", 50)
+	if got := tracker.end(stateTimedOut); got != stateTimedOut {
+		t.Errorf("end() = %v, want %v", got, stateTimedOut)
+	}
+
+	tracker = newCopyTracker()
+	tracker.observe("copying", 50)
+	if got := tracker.end(stateHandedOff); got != stateHandedOff || !got.terminal() {
+		t.Errorf("end() handed off = %v, want %v", got, stateHandedOff)
+	}
+
+	tracker = newCopyTracker()
+	tracker.observe("available", 0)
+	if got := tracker.end(stateTimedOut); got != stateAvailable {
+		t.Errorf("end() of a finished copy = %v, want %v", got, stateAvailable)
+	}
+}
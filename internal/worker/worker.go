@@ -1,123 +1,741 @@
 package worker
 
 import (
-	"os"
+	"context"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/rds"
 	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
 
 	"go.uber.org/zap"
 
+	"github.com/bluebenno/rds-snapshot-copier/internal/events"
+	"github.com/bluebenno/rds-snapshot-copier/internal/metrics"
 	"github.com/bluebenno/rds-snapshot-copier/internal/rdsops"
 	"github.com/bluebenno/rds-snapshot-copier/internal/snapops"
+	"github.com/bluebenno/rds-snapshot-copier/internal/throttle"
 	"github.com/bluebenno/rds-snapshot-copier/internal/wiring"
 )
 
-// Run wires things together and will start the infinite loop
-func Run(logger *zap.Logger, cfg *wiring.Config) error {
-	return Looper(logger, cfg)
+// newSession returns the RDS API of a region, as role. A var so tests can swap in a fake
+var newSession = func(cfg *wiring.Config, region, role string) (rdsiface.RDSAPI, error) {
+	s, err := wiring.Session(cfg, region, role)
+	if err != nil {
+		return nil, err // Not a typed nil
+	}
+	return s, nil
 }
 
-// Looper is an infinite loop.  Each loop will:
-// 1) Identify and then copy the snapshots from the source to the target region
+// callerAccount returns the AWS account of the credentials in use. A var so tests can swap in a fake
+var callerAccount = wiring.CallerAccount
+
+// pollEvery is how often a copy in progress is checked on
+var pollEvery = 10 * time.Second
+
+// Run wires things together and will start the loop, until ctx is cancelled
+// With cfg.EventQueue, snapshots are also copied as soon as the events for them arrive; cycles then catch up on
+// any missed. With reload, each cycle and event uses the config last reloaded.
+func Run(ctx context.Context, logger *zap.Logger, cfg *wiring.Config, reload *Reloader) error {
+	if reload == nil {
+		reload = NewReloader(logger, cfg, nil)
+	}
+	handle := func(ev *events.Event) {
+		handleEvent(ctx, logger, reload.Config(), ev)
+	}
+	slots := make(eventSlots, maxEvents)
+	if cfg.EventQueue != "" {
+		q, err := wiring.Queue(cfg, cfg.EventQueue)
+		if err != nil {
+			return err
+		}
+		go consume(ctx, logger, q, cfg.EventQueue, func(ev *events.Event) { slots.wait(ctx, handle, ev) })
+	}
+	if cfg.ListenAddr != "" {
+		go serve(ctx, logger, reload, cfg.ListenAddr, func(ev *events.Event) bool { return slots.try(handle, ev) })
+	}
+	return Looper(ctx, logger, cfg, reload)
+}
+
+// Looper runs a cycle every RunEvery minutes, until ctx is cancelled. With cfg.Once, a single cycle is run and
+// its error returned. With reload, a reloaded config is applied at the start of the next cycle. Each cycle will:
+// 1) Identify and then copy the snapshots from the source to each target region
 // 2) Optionally, encrypt the snapshots at the target region, with a supplied KMS key
 // 3) Optionally, housekeep snapshots at the target region
-func Looper(logger *zap.Logger, cfg *wiring.Config) error {
+func Looper(ctx context.Context, logger *zap.Logger, cfg *wiring.Config, reload *Reloader) error {
+	once := cfg.Once
 	for {
-		SrcRDSSource, err := wiring.Session(cfg, cfg.SourceRegion)
+		if reload != nil {
+			cfg = reload.apply()
+		}
+		report, err := Cycle(ctx, logger, cfg)
+		status.cycled()
+		report.Log(logger)
+		if cfg.Report != "" {
+			if werr := report.Write(cfg.Report); werr != nil {
+				logger.Warn("Failed to write the run report", zap.String("report", cfg.Report), zap.Error(werr))
+			}
+		}
+		if err == nil {
+			metrics.LastSuccess.SetToCurrentTime()
+		}
+		if once {
+			return err
+		}
+		if err != nil && ctx.Err() == nil {
+			logger.Error("Cycle failed", zap.String("source_region", cfg.SourceRegion), zap.Error(err))
+		}
+
+		// Sleep to next run
+		select {
+		case <-ctx.Done():
+			logger.Info("Stopped")
+			return nil
+		case <-time.After(every(cfg)):
+		}
+	}
+}
+
+// every returns the time between cycles, RunEvery minutes, defaulting to an hour
+func every(cfg *wiring.Config) time.Duration {
+	if cfg.RunEvery <= 0 {
+		return time.Hour
+	}
+	return time.Duration(cfg.RunEvery) * time.Minute
+}
+
+// Cycle identifies the in scope snapshots, then copies them to each target region in parallel, returning a report
+// of what was done. If ctx is cancelled, no more copies are started and those in flight are no longer waited for.
+func Cycle(ctx context.Context, logger *zap.Logger, cfg *wiring.Config) (*RunReport, error) {
+	report := NewRunReport(cfg.SourceRegion)
+	err := cycle(ctx, logger, cfg, report)
+	report.Error(err)
+	report.Finished()
+	return report, err
+}
+
+func cycle(ctx context.Context, logger *zap.Logger, cfg *wiring.Config, report *RunReport) error {
+	cfg, err := withSourceAccount(ctx, logger, cfg)
+	if err != nil {
+		return err
+	}
+
+	SrcRDSSource, err := newSession(cfg, cfg.SourceRegion, cfg.SourceRoleARN)
+	if err != nil {
+		logger.Error("Failed to create an AWS rds Session for the source region", zap.String("source_region", cfg.SourceRegion), zap.Error(err))
+		return err
+	}
+
+	inscopeRDS, inscopeClusters, err := inScope(ctx, logger, report, cfg, SrcRDSSource)
+	if err != nil {
+		return err
+	}
+	return copyAll(ctx, logger, report, cfg, SrcRDSSource, inscopeRDS, inscopeClusters)
+}
+
+// withSourceAccount returns cfg with SourceAccount set, from STS, when it is needed to tell if the target is another
+// account; that is when there is a TargetRoleARN but no SourceRoleARN
+func withSourceAccount(ctx context.Context, logger *zap.Logger, cfg *wiring.Config) (*wiring.Config, error) {
+	if cfg.TargetRoleARN == "" || cfg.SourceRoleARN != "" || cfg.SourceAccount != "" {
+		return cfg, nil
+	}
+	account, err := callerAccount(ctx, cfg)
+	if err != nil {
+		logger.Error("Failed to find the source account", zap.String("source_region", cfg.SourceRegion), zap.Error(err))
+		return nil, fmt.Errorf("failed to find the source account: %v", err)
+	}
+	c := *cfg
+	c.SourceAccount = account
+	return &c, nil
+}
+
+// copyAll copies the snapshots of the in scope rds instances and clusters to each of their target regions
+func copyAll(ctx context.Context, logger *zap.Logger, report *RunReport, cfg *wiring.Config, SrcRDSSource rdsiface.RDSAPI, inscopeRDS []rdsops.InScope, inscopeClusters []rdsops.InScopeCluster) error {
+	// Each target region is copied to in parallel, as the AWS copy limit is per destination region
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var failed []string
+	for _, region := range targetRegions(inscopeRDS, inscopeClusters) {
+		wg.Add(1)
+		go func(rcfg *wiring.Config) {
+			defer wg.Done()
+			num, err := copyToRegion(ctx, logger, report, rcfg, SrcRDSSource, inscopeRDS, inscopeClusters)
+			if err != nil {
+				report.Error(fmt.Errorf("%s: %v", rcfg.TargetRegion, err))
+				mu.Lock()
+				failed = append(failed, rcfg.TargetRegion)
+				mu.Unlock()
+			}
+			logger.Info("Region complete", zap.String("source_region", rcfg.SourceRegion), zap.String("target_region", rcfg.TargetRegion), zap.Int("copied", num), zap.Error(err))
+		}(cfg.ForRegion(region))
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("copy to regions %v failed", failed)
+	}
+	return nil
+}
+
+// inScope lists the rds instances and Aurora clusters in the source region, returning those flagged for copying
+func inScope(ctx context.Context, logger *zap.Logger, report *RunReport, cfg *wiring.Config, SrcRDSSource rdsiface.RDSAPI) ([]rdsops.InScope, []rdsops.InScopeCluster, error) {
+	var inscopeRDS []rdsops.InScope
+	if cfg.Instances() {
+		AllSourceRDS, err := rdsops.List(ctx, SrcRDSSource)
 		if err != nil {
-			logger.Fatal("Failed to create an AWS rds Session for the source region", zap.String("source_region", cfg.SourceRegion), zap.Error(err))
+			logger.Error("Failed to get a list of rds Instances", zap.String("source_region", cfg.SourceRegion), zap.Error(err))
+			return nil, nil, err
 		}
-		SrcRDSTarget, err := wiring.Session(cfg, cfg.TargetRegion)
+
+		var skipped []rdsops.Skipped
+		inscopeRDS, skipped, err = rdsops.Filter(ctx, logger, cfg, SrcRDSSource, AllSourceRDS)
 		if err != nil {
-			logger.Fatal("Failed to create an AWS rds Session for the target region", zap.String("target_region", cfg.TargetRegion), zap.Error(err))
+			logger.Error("Failed to find inscope rds Instances", zap.String("source_region", cfg.SourceRegion), zap.Error(err))
+			return nil, nil, err
 		}
+		for _, s := range skipped {
+			report.Skip(s.ID, "", "", s.Reason)
+		}
+	}
 
-		AllSourceRDS, err := rdsops.List(SrcRDSSource)
+	var inscopeClusters []rdsops.InScopeCluster
+	if cfg.Clusters() {
+		AllSourceClusters, err := rdsops.ListClusters(ctx, SrcRDSSource)
 		if err != nil {
-			logger.Fatal("Failed to get a list of rds Instances", zap.String("source_region", cfg.SourceRegion), zap.Error(err))
+			logger.Error("Failed to get a list of Aurora clusters", zap.String("source_region", cfg.SourceRegion), zap.Error(err))
+			return nil, nil, err
 		}
 
-		inscopeRDS, err := rdsops.Filter(logger, cfg, SrcRDSSource, AllSourceRDS)
+		var skipped []rdsops.Skipped
+		inscopeClusters, skipped, err = rdsops.FilterClusters(ctx, logger, cfg, SrcRDSSource, AllSourceClusters)
 		if err != nil {
-			logger.Fatal("Failed to find inscope rds Instances", zap.String("source_region", cfg.SourceRegion), zap.Error(err))
+			logger.Error("Failed to find inscope Aurora clusters", zap.String("source_region", cfg.SourceRegion), zap.Error(err))
+			return nil, nil, err
+		}
+		for _, s := range skipped {
+			report.Skip(s.ID, "", "", s.Reason)
 		}
+	}
 
-		ssq, err := buildQueue(logger, cfg, SrcRDSSource, SrcRDSTarget, inscopeRDS)
+	return inscopeRDS, inscopeClusters, nil
+}
+
+// copyToRegion copies the snapshots of the in scope rds instances and clusters which target cfg.TargetRegion
+func copyToRegion(ctx context.Context, logger *zap.Logger, report *RunReport, cfg *wiring.Config, SrcRDSSource rdsiface.RDSAPI, inscopeRDS []rdsops.InScope, inscopeClusters []rdsops.InScopeCluster) (int, error) {
+	SrcRDSTarget, err := newSession(cfg, cfg.TargetRegion, cfg.TargetRoleARN)
+	if err != nil {
+		logger.Error("Failed to create an AWS rds Session for the target region", zap.String("target_region", cfg.TargetRegion), zap.Error(err))
+		return 0, err
+	}
+
+	// Encrypted copies are presigned in the source region, by the account doing the copy
+	SrcRDSPresign := SrcRDSSource
+	if cfg.CrossAccount() != "" {
+		SrcRDSPresign, err = newSession(cfg, cfg.SourceRegion, cfg.TargetRoleARN)
 		if err != nil {
-			logger.Fatal("Failed to build a list of snapshots to copy", zap.String("source_region", cfg.SourceRegion), zap.Error(err))
+			logger.Error("Failed to create an AWS rds Session for the source region as the target account", zap.String("source_region", cfg.SourceRegion), zap.Error(err))
+			return 0, err
 		}
+	}
 
-		// The following will block until completed
-		num, _ := copySnapShots(logger, cfg, SrcRDSSource, SrcRDSTarget, ssq)
-		println(num)
-		os.Exit(1)
+	// Copies an earlier run started but did not finish with, e.g. handed off or left by a restart, are taken up
+	// ahead of new ones, whatever their state
+	resumed, err := resumable(ctx, logger, cfg.TargetRegion, SrcRDSSource, SrcRDSTarget, inscopeRDS, inscopeClusters)
+	if err != nil {
+		logger.Warn("Failed to list snapshot copies started by an earlier run", zap.String("target_region", cfg.TargetRegion), zap.Error(err))
+		report.Error(fmt.Errorf("%s: failed to list snapshot copies started by an earlier run: %v", cfg.TargetRegion, err))
+	}
+	targets := make(map[string]bool)
+	for _, j := range resumed {
+		targets[j.target] = true
+	}
 
-		// Sleep to next run
-		time.Sleep(time.Duration(cfg.RunEvery) * time.Minute)
+	ssq, err := buildQueue(ctx, logger, report, cfg.TargetRegion, SrcRDSSource, SrcRDSTarget, inscopeRDS, targets)
+	if err != nil {
+		logger.Error("Failed to build a list of snapshots to copy", zap.String("source_region", cfg.SourceRegion), zap.String("target_region", cfg.TargetRegion), zap.Error(err))
+		return 0, err
 	}
+
+	csq, err := buildClusterQueue(ctx, logger, report, cfg.TargetRegion, SrcRDSSource, SrcRDSTarget, inscopeClusters, targets)
+	if err != nil {
+		logger.Error("Failed to build a list of cluster snapshots to copy", zap.String("source_region", cfg.SourceRegion), zap.String("target_region", cfg.TargetRegion), zap.Error(err))
+		return 0, err
+	}
+
+	// The following will block until completed
+	queued := unresumed(append(ssq, csq...), resumed)
+	num, err := copySnapShots(ctx, logger, report, cfg, SrcRDSSource, SrcRDSPresign, SrcRDSTarget, append(resumed, queued...))
+
+	if ctx.Err() == nil {
+		report.AddDeleted(cfg.TargetRegion, housekeep(ctx, logger, cfg.TargetRegion, SrcRDSTarget, inscopeRDS, inscopeClusters))
+	}
+
+	return num, err
 }
 
-func copySnapShots(logger *zap.Logger, cfg *wiring.Config, srcRDSSource rdsiface.RDSAPI, srcRDSTarget rdsiface.RDSAPI, snaps []*rds.DBSnapshot) (int, error) {
+// housekeep applies retention to the copies of each in scope rds instance and cluster which targets region.
+// See snapops.Retain for the rules; with none set, all copies are kept. On a dry run, the rules keeping each copy
+// are logged. Returns the number of snapshots deleted.
+func housekeep(ctx context.Context, logger *zap.Logger, region string, srcRDSTarget rdsiface.RDSAPI, inscopeRDS []rdsops.InScope, inscopeClusters []rdsops.InScopeCluster) int {
+	var deleted int
+
+	for _, is := range inscopeRDS {
+		cfg := is.Config.ForRegion(region)
+		if !cfg.Retention() || !contains(is.Config.TargetRegions(), region) {
+			continue
+		}
 
-	type copyjob struct {
-		cfg          *wiring.Config
-		logger       *zap.Logger
-		snapshot     *rds.DBSnapshot
-		srcRDSSource rdsiface.RDSAPI
-		srcRDSTarget rdsiface.RDSAPI
+		decisions, expired, err := snapops.ListRetention(ctx, cfg, srcRDSTarget, is.Instance)
+		if err != nil {
+			logger.Warn("Failed to list expired snapshots", zap.String("region", region), zap.String("rds", *is.Instance.DBInstanceIdentifier), zap.Error(err))
+			continue
+		}
+		if cfg.DryRun {
+			logRetained(logger.With(zap.String("region", region), zap.String("rds", *is.Instance.DBInstanceIdentifier)), decisions)
+		}
+
+		for _, e := range expired {
+			logger.Info("Snapshot expired", zap.String("region", region), zap.String("rds", *is.Instance.DBInstanceIdentifier),
+				zap.String("snapshot", *e.DBSnapshotIdentifier), zap.Bool("dryrun", cfg.DryRun))
+		}
+		if cfg.DryRun || len(expired) == 0 {
+			continue
+		}
+
+		n, err := snapops.Delete(ctx, srcRDSTarget, expired)
+		if err != nil {
+			logger.Warn("Failed to delete expired snapshots", zap.String("region", region), zap.String("rds", *is.Instance.DBInstanceIdentifier), zap.Error(err))
+		}
+		metrics.SnapshotsDeleted.WithLabelValues(*is.Instance.DBInstanceIdentifier, region).Add(float64(n))
+		deleted += n
+	}
+
+	for _, is := range inscopeClusters {
+		cfg := is.Config.ForRegion(region)
+		if !cfg.Retention() || !contains(is.Config.TargetRegions(), region) {
+			continue
+		}
+
+		decisions, expired, err := snapops.ListRetentionCluster(ctx, cfg, srcRDSTarget, is.Cluster)
+		if err != nil {
+			logger.Warn("Failed to list expired cluster snapshots", zap.String("region", region), zap.String("cluster", *is.Cluster.DBClusterIdentifier), zap.Error(err))
+			continue
+		}
+		if cfg.DryRun {
+			logRetained(logger.With(zap.String("region", region), zap.String("cluster", *is.Cluster.DBClusterIdentifier)), decisions)
+		}
+
+		for _, e := range expired {
+			logger.Info("Cluster snapshot expired", zap.String("region", region), zap.String("cluster", *is.Cluster.DBClusterIdentifier),
+				zap.String("snapshot", *e.DBClusterSnapshotIdentifier), zap.Bool("dryrun", cfg.DryRun))
+		}
+		if cfg.DryRun || len(expired) == 0 {
+			continue
+		}
+
+		n, err := snapops.DeleteCluster(ctx, srcRDSTarget, expired)
+		if err != nil {
+			logger.Warn("Failed to delete expired cluster snapshots", zap.String("region", region), zap.String("cluster", *is.Cluster.DBClusterIdentifier), zap.Error(err))
+		}
+		metrics.SnapshotsDeleted.WithLabelValues(*is.Cluster.DBClusterIdentifier, region).Add(float64(n))
+		deleted += n
+	}
+
+	logger.Info("Housekeeping complete", zap.String("region", region), zap.Int("deleted", deleted))
+	return deleted
+}
+
+// logRetained reports the retention rules keeping each snapshot
+func logRetained(logger *zap.Logger, decisions []snapops.Decision) {
+	for _, d := range decisions {
+		if len(d.Keep) == 0 {
+			continue
+		}
+		logger.Info("Snapshot retained", zap.String("snapshot", d.Snapshot), zap.Time("created", aws.TimeValue(d.Created)), zap.Strings("keep", d.Keep))
+	}
+}
+
+// targetRegions returns every target region of the in scope rds instances and clusters
+func targetRegions(inscopeRDS []rdsops.InScope, inscopeClusters []rdsops.InScopeCluster) []string {
+	var regions []string
+	for _, i := range inscopeRDS {
+		for _, r := range i.Config.TargetRegions() {
+			if !contains(regions, r) {
+				regions = append(regions, r)
+			}
+		}
+	}
+	for _, c := range inscopeClusters {
+		for _, r := range c.Config.TargetRegions() {
+			if !contains(regions, r) {
+				regions = append(regions, r)
+			}
+		}
 	}
+	return regions
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// copyjob is a single source snapshot to be copied; either an rds instance snapshot or an Aurora cluster snapshot.
+// cfg is the config of the rds instance or cluster, for the target region being copied to
+type copyjob struct {
+	cfg       *wiring.Config
+	snapshot  *rds.DBSnapshot
+	cluster   *rds.DBClusterSnapshot
+	target    string // If set, the copy was started by an earlier run, as this snapshot in the target region
+	targetARN string // The ARN of target
+	share     string // If set, the manual copy of the automated source snapshot shared to copy it, see shareable
+}
+
+// source returns the rds instance or Aurora cluster identifier the snapshot was taken from
+func (j copyjob) source() string {
+	if j.cluster != nil {
+		return *j.cluster.DBClusterIdentifier
+	}
+	return *j.snapshot.DBInstanceIdentifier
+}
+
+// id returns the source snapshot identifier
+func (j copyjob) id() string {
+	if j.cluster != nil {
+		return *j.cluster.DBClusterSnapshotIdentifier
+	}
+	return *j.snapshot.DBSnapshotIdentifier
+}
+
+// arn returns the source snapshot ARN
+func (j copyjob) arn() *string {
+	if j.cluster != nil {
+		return j.cluster.DBClusterSnapshotArn
+	}
+	return j.snapshot.DBSnapshotArn
+}
+
+// storage returns the size of the source snapshot, in GiB
+func (j copyjob) storage() int64 {
+	if j.cluster != nil {
+		return aws.Int64Value(j.cluster.AllocatedStorage)
+	}
+	return aws.Int64Value(j.snapshot.AllocatedStorage)
+}
+
+// key identifies the copy of the source snapshot, by rds instance or Aurora cluster and snapshot identifier
+func (j copyjob) key() string {
+	return j.source() + "/" + j.id()
+}
 
+// automated reports if the source snapshot was taken by AWS, rather than manually
+func (j copyjob) automated() bool {
+	if j.cluster != nil {
+		return aws.StringValue(j.cluster.SnapshotType) == "automated"
+	}
+	return aws.StringValue(j.snapshot.SnapshotType) == "automated"
+}
+
+// inflight are the copies in progress in this process, by target region, rds and source snapshot, so that a cycle
+// and an event, or two events, do not both start or track the same copy
+var inflight = struct {
+	sync.Mutex
+	keys map[string]bool
+}{keys: make(map[string]bool)}
+
+// claim marks the copy of j to region as in progress, returning false if it already is. release unmarks it
+func claim(region string, j copyjob) (release func(), ok bool) {
+	key := region + "/" + j.key()
+	inflight.Lock()
+	defer inflight.Unlock()
+	if inflight.keys[key] {
+		return nil, false
+	}
+	inflight.keys[key] = true
+	return func() {
+		inflight.Lock()
+		delete(inflight.keys, key)
+		inflight.Unlock()
+	}, true
+}
+
+// copySnapShots copies the snapshots to the target region, with at most MaxCopyInFlight copies in progress there,
+// including those started by anything else. srcRDSPresign is a session
+// in the source region, as the target account, used to presign encrypted copies.
+// Returns the number copied, and an error if any copy failed. Once ctx is cancelled no more copies are started, and
+// those in flight are abandoned to complete in AWS.
+func copySnapShots(ctx context.Context, logger *zap.Logger, report *RunReport, cfg *wiring.Config, srcRDSSource, srcRDSPresign, srcRDSTarget rdsiface.RDSAPI, snaps []copyjob) (int, error) {
+
+	// result is the outcome of a single copy
 	type result struct {
-		worker int
-		start  time.Time
-		finish time.Time
-		result int
+		worker   int
+		snapshot string
+		start    time.Time
+		finish   time.Time
+		state    copyState
+		progress int64
+		err      error
 	}
 
 	var results []result
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 	ch := make(chan copyjob)
+	q := regionQuota(logger, cfg, srcRDSTarget)
 
-	for i := 1; i <= cfg.MaxCopyInFlight; i++ {
-		go func(i int) {
-			for j := range ch {
-				myresult := result{worker: i, start: time.Now()}
-				tName := strings.Replace((*j.snapshot.DBSnapshotIdentifier + "-copyfrom-" + j.cfg.SourceRegion), "rds:", "", -1)
-
-				res, err := copySnap(cfg, srcRDSSource, srcRDSTarget, j.snapshot)
-				if err != nil {
-					logger.Warn("Failed to perform snapshot pull", zap.String("source_region", cfg.SourceRegion), zap.String("target_region", cfg.TargetRegion),
-						zap.String("rds", *j.snapshot.DBInstanceIdentifier), zap.String("snapshot", *j.snapshot.DBSnapshotIdentifier), zap.Error(err))
-					continue
-				} else {
-					logger.Info("Snapshot copy started", zap.String("source_region", cfg.SourceRegion), zap.String("target_region", cfg.TargetRegion),
-						zap.String("rds", *j.snapshot.DBInstanceIdentifier), zap.String("source_snapshot", *j.snapshot.DBSnapshotIdentifier), zap.String("target_snapshot", tName), zap.Any("result", *res.DBSnapshot))
-				}
+	copyOne := func(i int, j copyjob) {
+		if ctx.Err() != nil {
+			return
+		}
+		myresult := result{worker: i, snapshot: j.id(), start: time.Now(), state: stateFailed}
+		defer func() {
+			myresult.finish = time.Now()
+			mu.Lock()
+			results = append(results, myresult)
+			mu.Unlock()
+			if myresult.state == "" {
+				return
+			}
+			o := Outcome{Source: j.source(), Snapshot: j.id(), TargetRegion: cfg.TargetRegion, Result: string(myresult.state),
+				Start: myresult.start, Finish: myresult.finish, AllocatedStorage: j.storage(), PercentProgress: myresult.progress}
+			if myresult.err != nil {
+				o.Error = myresult.err.Error()
+			}
+			report.Add(o)
+
+			switch myresult.state {
+			case stateAvailable:
+				metrics.CopiesSucceeded.WithLabelValues(j.source(), cfg.TargetRegion).Inc()
+				metrics.CopyDuration.WithLabelValues(cfg.TargetRegion).Observe(myresult.finish.Sub(myresult.start).Seconds())
+			case stateAbandoned, stateHandedOff:
+			default:
+				metrics.CopiesFailed.WithLabelValues(j.source(), cfg.TargetRegion, string(myresult.state)).Inc()
+			}
+		}()
+		release, ok := claim(cfg.TargetRegion, j)
+		if !ok {
+			logger.Info("Snapshot copy already in progress, skipping", zap.String("target_region", cfg.TargetRegion),
+				zap.String("rds", j.source()), zap.String("snapshot", j.id()))
+			myresult.state = "" // Tracked by whoever started it
+			report.Skip(j.source(), j.id(), cfg.TargetRegion, "copy already in progress")
+			return
+		}
+		defer release()
+
+		tName := snapops.TargetName(cfg, j.id())
+		if j.target != "" {
+			tName = j.target
+		}
+
+		// Each copy has its own deadline, as well as stopping with ctx
+		jctx, cancel := context.WithCancel(ctx)
+		if j.cfg.CopyTimeout > 0 {
+			jctx, cancel = context.WithTimeout(ctx, j.cfg.CopyTimeout)
+		}
+		defer cancel()
 
-				// poll until AWS has copied the snapshot has finished, this could be a long time if it is a very big/busy database
-				for {
-					status, err := snapops.Describe(srcRDSTarget, *res.DBSnapshot.DBSnapshotIdentifier)
-					if err != nil {
-						logger.Warn("Failed get status on nearly created sanpshot", zap.String("source_region", cfg.SourceRegion), zap.String("target_region", cfg.TargetRegion),
-							zap.String("rds", *j.snapshot.DBInstanceIdentifier), zap.String("snapshot", *j.snapshot.DBSnapshotIdentifier), zap.Error(err))
+		held := false
+		defer func() {
+			if held {
+				q.release()
+			}
+		}()
+		account := j.cfg.CrossAccount()
+		owner := true // Whether this started the copy, and so stops sharing the snapshot once it is done
+		sj := j       // The snapshot copied; for another account, a manual copy of an automated snapshot
+		tARN := j.targetARN
+		if j.target != "" {
+			// Left by a previous run; if still in progress it already holds one of the slots
+			q.take()
+			held = true
+			if j.share != "" {
+				sj = shareJob(j, j.share)
+			}
+			logger.Info("Resuming snapshot copy started by an earlier run", zap.String("source_region", cfg.SourceRegion), zap.String("target_region", cfg.TargetRegion),
+				zap.String("rds", j.source()), zap.String("source_snapshot", j.id()), zap.String("target_snapshot", tName))
+		} else {
+			// Another account can only copy the snapshot once it has been shared with it. Automated snapshots can not
+			// be shared, so a manual copy of them is shared instead
+			if account != "" {
+				if j.automated() {
+					var err error
+					var ready bool
+					if sj, ready, err = shareable(jctx, logger, srcRDSSource, j, cfg.TargetRegion, cfg.NoWait); err != nil {
+						if ctx.Err() != nil {
+							myresult.state = "" // Stopping before the copy started
+							return
+						}
+						if jctx.Err() != nil {
+							myresult.state = stateTimedOut
+						}
+						logger.Warn("Failed to make a manual copy of the automated snapshot to share", zap.String("source_region", cfg.SourceRegion),
+							zap.String("rds", j.source()), zap.String("snapshot", j.id()), zap.String("target_account", account), zap.Error(err))
+						myresult.err = err
+						return
 					}
-					time.Sleep(10 * time.Second)
-					if *status.Status == "available" {
-						break
+					if !ready {
+						// The manual copy carries on in AWS, and its tags have the next run pick it up
+						logger.Info("Manual copy of the automated snapshot to share in progress, not waiting for it", zap.String("source_region", cfg.SourceRegion),
+							zap.String("rds", j.source()), zap.String("snapshot", j.id()), zap.String("share_snapshot", sj.id()))
+						myresult.state = stateHandedOff
+						return
 					}
+				}
+				if err := shareSnap(jctx, srcRDSSource, sj, account); err != nil {
+					logger.Warn("Failed to share snapshot with the target account", zap.String("source_region", cfg.SourceRegion),
+						zap.String("rds", j.source()), zap.String("snapshot", sj.id()), zap.String("target_account", account), zap.Error(err))
+					myresult.err = err
+					dropShare(ctx, logger, srcRDSSource, j, sj)
+					return
+				}
+			}
 
+			// Wait for a slot in the target region, and back off while AWS refuses the copy for being over quota
+			var err error
+			for attempt := 0; ; attempt++ {
+				if err = q.acquire(jctx, func(others int) {
+					logger.Debug("Waiting for a snapshot copy slot in the target region", zap.String("target_region", cfg.TargetRegion),
+						zap.String("rds", j.source()), zap.String("snapshot", j.id()), zap.Int("others_in_progress", others), zap.Int("limit", cfg.MaxCopyInFlight))
+				}); err != nil {
+					break
+				}
+				held = true
+				tARN, err = copySnap(jctx, j.cfg, srcRDSPresign, srcRDSTarget, sj, tName, copyTags(j, sj))
+				if snapops.AlreadyExists(err) {
+					// Started by something else meanwhile, e.g. another instance of this tool; track it as if resumed
+					owner = false
+					err = nil
+					logger.Info("Snapshot copy already started, resuming it", zap.String("source_region", cfg.SourceRegion), zap.String("target_region", cfg.TargetRegion),
+						zap.String("rds", j.source()), zap.String("source_snapshot", j.id()), zap.String("target_snapshot", tName))
+					break
+				}
+				if err == nil || !snapops.QuotaExceeded(err) {
+					break
+				}
+				q.release()
+				held = false
+				wait := throttle.Backoff(attempt, quotaPoll, 16*quotaPoll)
+				logger.Warn("Snapshot copy quota exceeded in the target region, backing off", zap.String("target_region", cfg.TargetRegion),
+					zap.String("rds", j.source()), zap.String("snapshot", j.id()), zap.Duration("wait", wait), zap.Error(err))
+				select {
+				case <-jctx.Done():
+				case <-time.After(wait):
 				}
-				myresult.finish = time.Now()
-				myresult.result = 0
+			}
+			if err != nil && ctx.Err() != nil {
+				myresult.state = "" // Stopping before the copy started
+				return
+			}
+			if err != nil && jctx.Err() != nil {
+				myresult.state = stateTimedOut
+				err = fmt.Errorf("timed out waiting to start the copy: %v", err)
+			}
+			if err != nil {
+				unshareSnap(ctx, logger, srcRDSSource, sj, account)
+				dropShare(ctx, logger, srcRDSSource, j, sj)
+				logger.Warn("Failed to perform snapshot pull", zap.String("source_region", cfg.SourceRegion), zap.String("target_region", cfg.TargetRegion),
+					zap.String("rds", j.source()), zap.String("snapshot", j.id()), zap.Error(err))
+				myresult.err = err
+				return
+			}
+			if owner {
+				logger.Info("Snapshot copy started", zap.String("source_region", cfg.SourceRegion), zap.String("target_region", cfg.TargetRegion),
+					zap.String("rds", j.source()), zap.String("source_snapshot", j.id()), zap.String("target_snapshot", tName))
+				metrics.CopiesStarted.WithLabelValues(j.source(), cfg.TargetRegion).Inc()
+			}
+		}
+		metrics.CopiesInFlight.WithLabelValues(cfg.TargetRegion).Inc()
+		defer metrics.CopiesInFlight.WithLabelValues(cfg.TargetRegion).Dec()
 
-				mu.Lock()
-				results = append(results, myresult)
-				mu.Unlock()
+		// poll until AWS has copied the snapshot has finished, this could be a long time if it is a very big/busy database
+		tracker := newCopyTracker()
+		for !tracker.state.terminal() {
+			status, progress, err := describeTarget(jctx, srcRDSTarget, j, tName)
+			if err != nil && jctx.Err() == nil {
+				logger.Warn("Failed get status on nearly created sanpshot", zap.String("source_region", cfg.SourceRegion), zap.String("target_region", cfg.TargetRegion),
+					zap.String("rds", j.source()), zap.String("snapshot", j.id()), zap.Error(err))
+			}
+			if err == nil {
+				before := tracker.progress
+				if tracker.observe(status, progress) == stateCopying && tracker.progress != before {
+					logger.Debug("Snapshot copy progress", zap.String("target_region", cfg.TargetRegion), zap.String("target_snapshot", tName), zap.Int64("percent", tracker.progress))
+				}
+				if tracker.state.terminal() {
+					break
+				}
+			}
+			if cfg.NoWait {
+				tracker.end(stateHandedOff)
+				break
+			}
+
+			select {
+			case <-jctx.Done():
+				if ctx.Err() != nil {
+					tracker.end(stateAbandoned)
+				} else {
+					tracker.end(stateTimedOut)
+				}
+			case <-time.After(pollEvery):
+			}
+		}
+		myresult.state = tracker.state
+		myresult.progress = tracker.progress
+
+		switch tracker.state {
+		case stateAvailable:
+			logger.Info("Snapshot copy complete", zap.String("source_region", cfg.SourceRegion), zap.String("target_region", cfg.TargetRegion),
+				zap.String("rds", j.source()), zap.String("target_snapshot", tName))
+			if owner && tARN != "" {
+				if err := snapops.Untag(ctx, srcRDSTarget, tARN); err != nil {
+					logger.Warn("Failed to untag the snapshot copy, the next run will check on it again", zap.String("target_region", cfg.TargetRegion),
+						zap.String("rds", j.source()), zap.String("target_snapshot", tName), zap.Error(err))
+				}
+			}
+		case stateAbandoned:
+			// Stopping; the copy carries on in AWS, so the snapshot, or its manual copy, is left shared with the target account
+			logger.Warn("Stopped waiting for snapshot copy, it will complete in AWS", zap.String("source_region", cfg.SourceRegion), zap.String("target_region", cfg.TargetRegion),
+				zap.String("rds", j.source()), zap.String("target_snapshot", tName), zap.String("target_account", account), zap.Int64("percent", tracker.progress))
+			return
+		case stateHandedOff:
+			// The copy carries on in AWS, and its tags have the next run pick it up; so the snapshot, or its manual
+			// copy, is left shared with the target account
+			logger.Info("Snapshot copy in progress, not waiting for it", zap.String("source_region", cfg.SourceRegion), zap.String("target_region", cfg.TargetRegion),
+				zap.String("rds", j.source()), zap.String("target_snapshot", tName), zap.Int64("percent", tracker.progress))
+			return
+		case stateTimedOut, stateFailed:
+			// Remove the partial or failed copy, so it is retried next cycle
+			myresult.err = fmt.Errorf("snapshot copy %s at %d%%", tracker.state, tracker.progress)
+			logger.Warn("Snapshot copy did not complete, deleting it", zap.String("source_region", cfg.SourceRegion), zap.String("target_region", cfg.TargetRegion),
+				zap.String("rds", j.source()), zap.String("target_snapshot", tName), zap.String("state", string(tracker.state)), zap.Int64("percent", tracker.progress),
+				zap.Duration("timeout", j.cfg.CopyTimeout))
+			abortCopy(ctx, logger, srcRDSTarget, j, tName)
+		case stateVanished:
+			myresult.err = fmt.Errorf("snapshot copy %s", tracker.state)
+			logger.Warn("Snapshot copy vanished from the target region", zap.String("source_region", cfg.SourceRegion), zap.String("target_region", cfg.TargetRegion),
+				zap.String("rds", j.source()), zap.String("target_snapshot", tName))
+		}
+		if owner {
+			unshareSnap(ctx, logger, srcRDSSource, sj, account)
+			dropShare(ctx, logger, srcRDSSource, j, sj)
+		}
+	}
+
+	metrics.CopiesInFlightLimit.WithLabelValues(cfg.TargetRegion).Set(float64(cfg.MaxCopyInFlight))
+	for i := 1; i <= cfg.MaxCopyInFlight; i++ {
+		go func(i int) {
+			for j := range ch {
+				copyOne(i, j)
 				wg.Done()
 			}
 		}(i)
@@ -125,80 +743,410 @@ func copySnapShots(logger *zap.Logger, cfg *wiring.Config, srcRDSSource rdsiface
 
 	for _, s := range snaps {
 		wg.Add(1)
-		ch <- copyjob{
-			cfg:          cfg,
-			logger:       logger,
-			snapshot:     s,
-			srcRDSSource: srcRDSSource,
-			srcRDSTarget: srcRDSTarget,
-		}
+		ch <- s
 	}
 	close(ch)
 	wg.Wait()
 
-	return 0, nil
+	var copied, failed int
+	for _, r := range results {
+		switch r.state {
+		case stateAvailable:
+			copied++
+		case "", stateAbandoned, stateHandedOff:
+		default:
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return copied, fmt.Errorf("%d of %d snapshot copies failed", failed, len(snaps))
+	}
+	return copied, nil
 }
 
-// Copy a single snapshot
-func copySnap(cfg *wiring.Config, srcRDSSource rdsiface.RDSAPI, srcRDSTarget rdsiface.RDSAPI, s *rds.DBSnapshot) (*rds.CopyDBSnapshotOutput, error) {
+// Copy a single snapshot, returning the ARN of the copy
+func copySnap(ctx context.Context, cfg *wiring.Config, srcRDSSource rdsiface.RDSAPI, srcRDSTarget rdsiface.RDSAPI, j copyjob, tName string, tags []*rds.Tag) (string, error) {
+	if j.cluster != nil {
+		var out *rds.CopyDBClusterSnapshotOutput
+		var err error
+		if cfg.TargetKMS != "" {
+			out, err = snapops.PullEncryptedClusterSnapShot(ctx, cfg, srcRDSSource, srcRDSTarget, j.arn(), tName, tags)
+		} else {
+			out, err = snapops.PullClusterSnapShot(ctx, cfg, srcRDSTarget, j.arn(), tName, tags)
+		}
+		if err != nil || out.DBClusterSnapshot == nil {
+			return "", err
+		}
+		return aws.StringValue(out.DBClusterSnapshot.DBClusterSnapshotArn), nil
+	}
 
-	tName := strings.Replace((*s.DBSnapshotIdentifier + "-cf-" + cfg.SourceRegion), "rds:", "", -1)
+	var out *rds.CopyDBSnapshotOutput
+	var err error
+	if cfg.TargetKMS != "" {
+		out, err = snapops.PullEncryptedSnapShot(ctx, cfg, srcRDSSource, srcRDSTarget, j.arn(), tName, tags)
+	} else {
+		out, err = snapops.PullSnapShot(ctx, cfg, srcRDSTarget, j.arn(), tName, tags)
+	}
+	if err != nil || out.DBSnapshot == nil {
+		return "", err
+	}
+	return aws.StringValue(out.DBSnapshot.DBSnapshotArn), nil
+}
+
+// copyTags are the tags of the copy of j, made from sj, recording it until this tool has finished with it. See
+// snapops.SourceTag
+func copyTags(j, sj copyjob) []*rds.Tag {
+	tags := []*rds.Tag{{Key: aws.String(snapops.SourceTag), Value: aws.String(j.id())}}
+	if sj.id() != j.id() {
+		tags = append(tags, &rds.Tag{Key: aws.String(snapops.ShareTag), Value: aws.String(sj.id())})
+	}
+	return tags
+}
+
+// shareSnap shares the source snapshot with another AWS account
+func shareSnap(ctx context.Context, srcRDSSource rdsiface.RDSAPI, j copyjob, account string) error {
+	if j.cluster != nil {
+		return snapops.ShareCluster(ctx, srcRDSSource, j.id(), account)
+	}
+	return snapops.Share(ctx, srcRDSSource, j.id(), account)
+}
+
+// unshareSnap stops sharing the source snapshot with another AWS account. account may be "", meaning it was never shared
+func unshareSnap(ctx context.Context, logger *zap.Logger, srcRDSSource rdsiface.RDSAPI, j copyjob, account string) {
+	if account == "" {
+		return
+	}
 
-	var res *rds.CopyDBSnapshotOutput
 	var err error
+	if j.cluster != nil {
+		err = snapops.UnshareCluster(ctx, srcRDSSource, j.id(), account)
+	} else {
+		err = snapops.Unshare(ctx, srcRDSSource, j.id(), account)
+	}
+	if err != nil {
+		logger.Warn("Failed to unshare snapshot with the target account", zap.String("rds", j.source()), zap.String("snapshot", j.id()), zap.String("target_account", account), zap.Error(err))
+	}
+}
 
-	if cfg.TargetKMS != "" {
-		res, err = snapops.PullEncryptedSnapShot(cfg, srcRDSSource, srcRDSTarget, s.DBSnapshotArn, tName)
+// shareJob returns j for name, the manual copy of its automated snapshot made in the source region to be shared
+// with another account. See snapops.ShareName
+func shareJob(j copyjob, name string) copyjob {
+	var arn *string
+	if j.arn() != nil {
+		arn = aws.String(strings.TrimSuffix(*j.arn(), j.id()) + name)
+	}
+	if j.cluster != nil {
+		c := *j.cluster
+		c.DBClusterSnapshotIdentifier, c.DBClusterSnapshotArn, c.SnapshotType = aws.String(name), arn, aws.String("manual")
+		j.cluster = &c
+		return j
+	}
+	s := *j.snapshot
+	s.DBSnapshotIdentifier, s.DBSnapshotArn, s.SnapshotType = aws.String(name), arn, aws.String("manual")
+	j.snapshot = &s
+	return j
+}
+
+// shareable makes a manual copy of j's automated snapshot in the source region, so that it can be shared with the
+// account copying it to region, and returns the job for it once it is available. The copy is tagged with
+// snapops.SourceTag, so that one left by an earlier run is picked up, see resumable, and reused; one which fails is
+// deleted. With noWait, ready is false if the copy is still in progress, rather than waiting for it.
+func shareable(ctx context.Context, logger *zap.Logger, srcRDSSource rdsiface.RDSAPI, j copyjob, region string, noWait bool) (sj copyjob, ready bool, err error) {
+	sj = shareJob(j, snapops.ShareName(j.id(), region))
+	if j.share == "" {
+		tags := []*rds.Tag{{Key: aws.String(snapops.SourceTag), Value: aws.String(j.id())}}
+		if j.cluster != nil {
+			_, err = snapops.CopyLocalCluster(ctx, srcRDSSource, j.id(), sj.id(), tags)
+		} else {
+			_, err = snapops.CopyLocal(ctx, srcRDSSource, j.id(), sj.id(), tags)
+		}
+		if err != nil && !snapops.AlreadyExists(err) {
+			return j, false, err
+		}
+	}
+	if !noWait {
+		logger.Info("Waiting for the manual copy of the automated snapshot to share", zap.String("rds", j.source()),
+			zap.String("snapshot", j.id()), zap.String("share_snapshot", sj.id()))
+	}
+
+	for {
+		status, _, err := describeTarget(ctx, srcRDSSource, sj, sj.id())
+		switch {
+		case err != nil && ctx.Err() == nil:
+			logger.Warn("Failed to get the status of the manual copy to share", zap.String("rds", j.source()), zap.String("share_snapshot", sj.id()), zap.Error(err))
+		case err != nil:
+			return j, false, err
+		case status == "available":
+			return sj, true, nil
+		case status == "":
+			return j, false, fmt.Errorf("manual copy %s vanished", sj.id())
+		case !snapops.InProgress(status):
+			dropShare(ctx, logger, srcRDSSource, j, sj)
+			return j, false, fmt.Errorf("manual copy %s %s", sj.id(), status)
+		}
+		if noWait {
+			return sj, false, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return j, false, ctx.Err()
+		case <-time.After(pollEvery):
+		}
+	}
+}
+
+// dropShare deletes sj, the manual copy of j's automated snapshot shared with another account, once done with.
+// It does nothing if sj is j, i.e. the snapshot shared was j's own.
+func dropShare(ctx context.Context, logger *zap.Logger, srcRDSSource rdsiface.RDSAPI, j, sj copyjob) {
+	if sj.id() == j.id() {
+		return
+	}
+
+	var err error
+	if sj.cluster != nil {
+		_, err = snapops.DeleteCluster(ctx, srcRDSSource, []*rds.DBClusterSnapshot{sj.cluster})
 	} else {
-		res, err = snapops.PullSnapShot(cfg, srcRDSTarget, s.DBSnapshotArn, tName)
+		_, err = snapops.Delete(ctx, srcRDSSource, []*rds.DBSnapshot{sj.snapshot})
+	}
+	if err != nil {
+		logger.Warn("Failed to delete the manual copy of the snapshot shared with the target account", zap.String("rds", j.source()),
+			zap.String("share_snapshot", sj.id()), zap.Error(err))
 	}
+}
 
+// abortCopy cancels a copy in progress, or removes a failed copy, by deleting the snapshot in the target region
+func abortCopy(ctx context.Context, logger *zap.Logger, srcRDSTarget rdsiface.RDSAPI, j copyjob, tName string) {
+	var err error
+	if j.cluster != nil {
+		_, err = snapops.DeleteCluster(ctx, srcRDSTarget, []*rds.DBClusterSnapshot{{DBClusterSnapshotIdentifier: aws.String(tName)}})
+	} else {
+		_, err = snapops.Delete(ctx, srcRDSTarget, []*rds.DBSnapshot{{DBSnapshotIdentifier: aws.String(tName)}})
+	}
 	if err != nil {
-		return nil, err
+		logger.Warn("Failed to abort snapshot copy", zap.String("rds", j.source()), zap.String("target_snapshot", tName), zap.Error(err))
 	}
+}
 
-	return res, nil
+// describeTarget returns the status and PercentProgress of a snapshot in the target region, or "" if it does not exist
+func describeTarget(ctx context.Context, srcRDSTarget rdsiface.RDSAPI, j copyjob, tName string) (string, int64, error) {
+	if j.cluster != nil {
+		s, err := snapops.DescribeCluster(ctx, srcRDSTarget, tName)
+		if err != nil || s == nil {
+			return "", 0, err
+		}
+		return aws.StringValue(s.Status), aws.Int64Value(s.PercentProgress), nil
+	}
+
+	s, err := snapops.Describe(ctx, srcRDSTarget, tName)
+	if err != nil || s == nil {
+		return "", 0, err
+	}
+	return aws.StringValue(s.Status), aws.Int64Value(s.PercentProgress), nil
+}
+
+// due reports if an rds is due a copy under its schedule, given when its most recent copy was taken
+func due(cfg *wiring.Config, latest *time.Time) bool {
+	return cfg.Schedule <= 0 || latest == nil || time.Since(*latest) >= cfg.Schedule
 }
 
-// buildQueue will build a list of the (latest) snapshots for each rds
-func buildQueue(logger *zap.Logger, cfg *wiring.Config, rdssession rdsiface.RDSAPI, srcRDSTarget rdsiface.RDSAPI, isr []*rds.DBInstance) ([]*rds.DBSnapshot, error) {
-	var toCopy []*rds.DBSnapshot
+// buildQueue will build a list of the snapshots to copy for each rds which targets region.
+// That is the latest snapshot or, with a Lookback, every snapshot missing from the target region. resumed are the
+// copies, by target snapshot, already queued as started by an earlier run.
+func buildQueue(ctx context.Context, logger *zap.Logger, report *RunReport, region string, rdssession rdsiface.RDSAPI, srcRDSTarget rdsiface.RDSAPI, isr []rdsops.InScope, resumed map[string]bool) ([]copyjob, error) {
+	var toCopy []copyjob
 
-	for _, i := range isr {
+	for _, is := range isr {
+		if !contains(is.Config.TargetRegions(), region) {
+			continue
+		}
+		cfg := is.Config.ForRegion(region)
+		i := is.Instance
 		logger.Info("Looking at rds", zap.String("RDS", *i.DBInstanceIdentifier))
 
-		lsSource, err := snapops.List(rdssession, *i.DBInstanceIdentifier)
+		lsSource, err := snapops.List(ctx, rdssession, *i.DBInstanceIdentifier)
 		if err != nil {
 			logger.Warn("Failed to list snapshots", zap.String("region", cfg.SourceRegion), zap.String("rds", *i.DBInstanceIdentifier), zap.Error(err))
+			report.Skip(*i.DBInstanceIdentifier, "", cfg.TargetRegion, "failed to list snapshots: "+err.Error())
+			continue
+		}
+
+		if cfg.Lookback > 0 {
+			lsTarget, err := snapops.List(ctx, srcRDSTarget, *i.DBInstanceIdentifier)
+			if err != nil {
+				logger.Warn("Failed to list snapshots", zap.String("region", cfg.TargetRegion), zap.String("rds", *i.DBInstanceIdentifier), zap.Error(err))
+				report.Skip(*i.DBInstanceIdentifier, "", cfg.TargetRegion, "failed to list snapshots: "+err.Error())
+				continue
+			}
+			latestT, _ := snapops.GetLatest(lsTarget)
+			if latestT != nil && !due(cfg, latestT.SnapshotCreateTime) {
+				logger.Info("Not yet due for copy", zap.String("region", cfg.TargetRegion), zap.String("rds", *i.DBInstanceIdentifier), zap.Duration("schedule", cfg.Schedule))
+				report.Skip(*i.DBInstanceIdentifier, "", cfg.TargetRegion, "not yet due under its schedule")
+				continue
+			}
+
+			for _, s := range snapops.Missing(cfg, lsSource, lsTarget, time.Now().Add(-cfg.Lookback)) {
+				logger.Info("enqueue snapshot for copy", zap.String("region", cfg.SourceRegion), zap.String("rds", *i.DBInstanceIdentifier), zap.String("snapshot", *s.DBSnapshotIdentifier))
+				toCopy = append(toCopy, copyjob{cfg: cfg, snapshot: s})
+			}
 			continue
 		}
 
 		latestS, err := snapops.GetLatest(lsSource)
 		if err != nil {
 			logger.Warn("Failed to find latest snapshot", zap.String("region", cfg.SourceRegion), zap.String("rds", *i.DBInstanceIdentifier), zap.Error(err))
+			report.Skip(*i.DBInstanceIdentifier, "", cfg.TargetRegion, "failed to find the latest snapshot: "+err.Error())
 			continue
 		}
 
 		if latestS == nil {
 			logger.Info("No source snapshots found", zap.String("region", cfg.SourceRegion), zap.String("rds", *i.DBInstanceIdentifier))
+			report.Skip(*i.DBInstanceIdentifier, "", cfg.TargetRegion, "no source snapshots")
 			continue
 		}
 
 		// Has it already been copied?
-		tName := strings.Replace((*latestS.DBSnapshotIdentifier + "-cf-" + cfg.SourceRegion), "rds:", "", -1)
-		exists, err := snapops.Describe(srcRDSTarget, tName)
+		tName := snapops.TargetName(cfg, *latestS.DBSnapshotIdentifier)
+		exists, err := snapops.Describe(ctx, srcRDSTarget, tName)
 		if err != nil {
 			logger.Warn("Failed to search for snapshot at target region", zap.String("region", cfg.TargetRegion), zap.String("snapshot", tName), zap.Error(err))
+			report.Skip(*i.DBInstanceIdentifier, *latestS.DBSnapshotIdentifier, cfg.TargetRegion, "failed to search the target region: "+err.Error())
+			continue
+		}
+
+		if exists != nil && resumed[tName] {
+			continue
+		}
+
+		if exists != nil && snapops.InProgress(aws.StringValue(exists.Status)) {
+			logger.Info("Snapshot copy already in progress in target region", zap.String("region", cfg.TargetRegion), zap.String("snapshot", tName))
 			continue
 		}
 
 		if exists != nil {
 			logger.Info("Snapshot already found in target region", zap.String("region", cfg.TargetRegion), zap.String("snapshot", tName), zap.Error(err))
+			report.Skip(*i.DBInstanceIdentifier, *latestS.DBSnapshotIdentifier, cfg.TargetRegion, "already copied")
 			continue
 		}
 
+		if cfg.Schedule > 0 {
+			lsTarget, err := snapops.List(ctx, srcRDSTarget, *i.DBInstanceIdentifier)
+			if err != nil {
+				logger.Warn("Failed to list snapshots", zap.String("region", cfg.TargetRegion), zap.String("rds", *i.DBInstanceIdentifier), zap.Error(err))
+				report.Skip(*i.DBInstanceIdentifier, "", cfg.TargetRegion, "failed to list snapshots: "+err.Error())
+				continue
+			}
+			latestT, _ := snapops.GetLatest(lsTarget)
+			if latestT != nil && !due(cfg, latestT.SnapshotCreateTime) {
+				logger.Info("Not yet due for copy", zap.String("region", cfg.TargetRegion), zap.String("rds", *i.DBInstanceIdentifier), zap.Duration("schedule", cfg.Schedule))
+				report.Skip(*i.DBInstanceIdentifier, "", cfg.TargetRegion, "not yet due under its schedule")
+				continue
+			}
+		}
+
 		logger.Info("enqueue snapshot for copy", zap.String("region", cfg.SourceRegion), zap.String("rds", *i.DBInstanceIdentifier), zap.String("snapshot", *latestS.DBSnapshotIdentifier))
-		toCopy = append(toCopy, latestS)
+		toCopy = append(toCopy, copyjob{cfg: cfg, snapshot: latestS})
+	}
+	return toCopy, nil
+}
+
+// buildClusterQueue will build a list of the snapshots to copy for each Aurora cluster which targets region.
+// That is the latest snapshot or, with a Lookback, every snapshot missing from the target region. resumed is as
+// per buildQueue.
+func buildClusterQueue(ctx context.Context, logger *zap.Logger, report *RunReport, region string, rdssession rdsiface.RDSAPI, srcRDSTarget rdsiface.RDSAPI, isc []rdsops.InScopeCluster, resumed map[string]bool) ([]copyjob, error) {
+	var toCopy []copyjob
+
+	for _, is := range isc {
+		if !contains(is.Config.TargetRegions(), region) {
+			continue
+		}
+		cfg := is.Config.ForRegion(region)
+		c := is.Cluster
+		logger.Info("Looking at cluster", zap.String("cluster", *c.DBClusterIdentifier))
+
+		lsSource, err := snapops.ListCluster(ctx, rdssession, *c.DBClusterIdentifier)
+		if err != nil {
+			logger.Warn("Failed to list cluster snapshots", zap.String("region", cfg.SourceRegion), zap.String("cluster", *c.DBClusterIdentifier), zap.Error(err))
+			report.Skip(*c.DBClusterIdentifier, "", cfg.TargetRegion, "failed to list snapshots: "+err.Error())
+			continue
+		}
+
+		if cfg.Lookback > 0 {
+			lsTarget, err := snapops.ListCluster(ctx, srcRDSTarget, *c.DBClusterIdentifier)
+			if err != nil {
+				logger.Warn("Failed to list cluster snapshots", zap.String("region", cfg.TargetRegion), zap.String("cluster", *c.DBClusterIdentifier), zap.Error(err))
+				report.Skip(*c.DBClusterIdentifier, "", cfg.TargetRegion, "failed to list snapshots: "+err.Error())
+				continue
+			}
+			latestT, _ := snapops.GetLatestCluster(lsTarget)
+			if latestT != nil && !due(cfg, latestT.SnapshotCreateTime) {
+				logger.Info("Not yet due for copy", zap.String("region", cfg.TargetRegion), zap.String("cluster", *c.DBClusterIdentifier), zap.Duration("schedule", cfg.Schedule))
+				report.Skip(*c.DBClusterIdentifier, "", cfg.TargetRegion, "not yet due under its schedule")
+				continue
+			}
+
+			for _, s := range snapops.MissingCluster(cfg, lsSource, lsTarget, time.Now().Add(-cfg.Lookback)) {
+				logger.Info("enqueue cluster snapshot for copy", zap.String("region", cfg.SourceRegion), zap.String("cluster", *c.DBClusterIdentifier), zap.String("snapshot", *s.DBClusterSnapshotIdentifier))
+				toCopy = append(toCopy, copyjob{cfg: cfg, cluster: s})
+			}
+			continue
+		}
+
+		latestS, err := snapops.GetLatestCluster(lsSource)
+		if err != nil {
+			logger.Warn("Failed to find latest cluster snapshot", zap.String("region", cfg.SourceRegion), zap.String("cluster", *c.DBClusterIdentifier), zap.Error(err))
+			report.Skip(*c.DBClusterIdentifier, "", cfg.TargetRegion, "failed to find the latest snapshot: "+err.Error())
+			continue
+		}
+
+		if latestS == nil {
+			logger.Info("No source cluster snapshots found", zap.String("region", cfg.SourceRegion), zap.String("cluster", *c.DBClusterIdentifier))
+			report.Skip(*c.DBClusterIdentifier, "", cfg.TargetRegion, "no source snapshots")
+			continue
+		}
+
+		// Has it already been copied?
+		tName := snapops.TargetName(cfg, *latestS.DBClusterSnapshotIdentifier)
+		exists, err := snapops.DescribeCluster(ctx, srcRDSTarget, tName)
+		if err != nil {
+			logger.Warn("Failed to search for cluster snapshot at target region", zap.String("region", cfg.TargetRegion), zap.String("snapshot", tName), zap.Error(err))
+			report.Skip(*c.DBClusterIdentifier, *latestS.DBClusterSnapshotIdentifier, cfg.TargetRegion, "failed to search the target region: "+err.Error())
+			continue
+		}
+
+		if exists != nil && resumed[tName] {
+			continue
+		}
+
+		if exists != nil && snapops.InProgress(aws.StringValue(exists.Status)) {
+			logger.Info("Cluster snapshot copy already in progress in target region", zap.String("region", cfg.TargetRegion), zap.String("snapshot", tName))
+			continue
+		}
+
+		if exists != nil {
+			logger.Info("Cluster snapshot already found in target region", zap.String("region", cfg.TargetRegion), zap.String("snapshot", tName))
+			report.Skip(*c.DBClusterIdentifier, *latestS.DBClusterSnapshotIdentifier, cfg.TargetRegion, "already copied")
+			continue
+		}
+
+		if cfg.Schedule > 0 {
+			lsTarget, err := snapops.ListCluster(ctx, srcRDSTarget, *c.DBClusterIdentifier)
+			if err != nil {
+				logger.Warn("Failed to list cluster snapshots", zap.String("region", cfg.TargetRegion), zap.String("cluster", *c.DBClusterIdentifier), zap.Error(err))
+				report.Skip(*c.DBClusterIdentifier, "", cfg.TargetRegion, "failed to list snapshots: "+err.Error())
+				continue
+			}
+			latestT, _ := snapops.GetLatestCluster(lsTarget)
+			if latestT != nil && !due(cfg, latestT.SnapshotCreateTime) {
+				logger.Info("Not yet due for copy", zap.String("region", cfg.TargetRegion), zap.String("cluster", *c.DBClusterIdentifier), zap.Duration("schedule", cfg.Schedule))
+				report.Skip(*c.DBClusterIdentifier, "", cfg.TargetRegion, "not yet due under its schedule")
+				continue
+			}
+		}
+
+		logger.Info("enqueue cluster snapshot for copy", zap.String("region", cfg.SourceRegion), zap.String("cluster", *c.DBClusterIdentifier), zap.String("snapshot", *latestS.DBClusterSnapshotIdentifier))
+		toCopy = append(toCopy, copyjob{cfg: cfg, cluster: latestS})
 	}
 	return toCopy, nil
 }